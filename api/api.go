@@ -5,6 +5,8 @@ package api
 // URL constants
 const (
 	sttEndpoint       = "/v1/stt/"
+	sttStreamEndpoint = "/v1/stt/stream/"
 	ttsEndpoint       = "/v1/tts/"
+	ttsVoicesEndpoint = "/v1/tts/voices/"
 	interpretEndpoint = "/v1/interpret/"
 )
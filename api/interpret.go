@@ -3,9 +3,36 @@ package api
 import (
 	"encoding/json"
 	"net/url"
+	"time"
 
 	"github.com/auroraapi/aurora-go/api/backend"
 	"github.com/auroraapi/aurora-go/config"
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+// EntityType is the kind of value an entity holds, which says which field of
+// its `EntityValue` is set.
+type EntityType string
+
+const (
+	// EntityText is free text, such as a song name. Only `EntityValue.Text`
+	// is set.
+	EntityText EntityType = "text"
+	// EntityNumber is a number, such as "twenty five" or "3.5". It is in
+	// `EntityValue.Number`.
+	EntityNumber EntityType = "number"
+	// EntityDate is a date, such as "tomorrow" or "March 3rd". It is in
+	// `EntityValue.Time`, at midnight.
+	EntityDate EntityType = "date"
+	// EntityTime is a time, or a date and time, such as "5pm" or "tomorrow
+	// morning". It is in `EntityValue.Time`.
+	EntityTime EntityType = "time"
+	// EntityDuration is a length of time, such as "ten minutes". It is in
+	// `EntityValue.Seconds` (see `EntityValue.Duration`).
+	EntityDuration EntityType = "duration"
+	// EntityLocation is a place, such as "los angeles". It is in
+	// `EntityValue.Location`.
+	EntityLocation EntityType = "location"
 )
 
 // InterpretResponse is the response returned by the API if the text was
@@ -22,6 +49,73 @@ type InterpretResponse struct {
 	// are the entity name (like song or location) and the value
 	// is the detected value for that entitity
 	Entities map[string]string `json:"entities"`
+
+	// Confidence is how confident the API is in `Intent`, from 0 to 1.
+	Confidence float64 `json:"confidence"`
+
+	// Intents are the candidate intents, ranked from the most to the least
+	// likely. The first is `Intent`, unless the intent was unclear.
+	Intents []*InterpretIntent `json:"intents"`
+
+	// EntityDetails are the entities of `Entities`, in the order they appear
+	// in the text, with their types, positions and normalized values.
+	EntityDetails []*InterpretEntity `json:"entity_details"`
+}
+
+// InterpretIntent is a candidate intent for a query.
+type InterpretIntent struct {
+	// Intent is the name of the intent.
+	Intent string `json:"intent"`
+	// Score is how likely the intent is, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// InterpretEntity is an entity detected in a query.
+type InterpretEntity struct {
+	// Name is the name of the entity (its key in `Entities`).
+	Name string `json:"name"`
+	// Type is the kind of value the entity holds.
+	Type EntityType `json:"type"`
+	// Text is the entity as it appears in the query.
+	Text string `json:"text"`
+	// Start and End are the positions (in characters) of the first
+	// character of the entity in the query and the character after it.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Confidence is how confident the API is in the entity, from 0 to 1.
+	Confidence float64 `json:"confidence"`
+	// Value is the normalized value of the entity, e.g. the date that
+	// "tomorrow" refers to.
+	Value *EntityValue `json:"value"`
+}
+
+// EntityValue is the normalized value of an entity. `Text` is always set,
+// and the other field that is set depends on the entity's type.
+type EntityValue struct {
+	// Text is the value as text (the same as the value in `Entities`).
+	Text string `json:"text"`
+	// Number is the value of a number.
+	Number float64 `json:"number"`
+	// Time is the value of a date or time.
+	Time time.Time `json:"time"`
+	// Seconds is the length of a duration.
+	Seconds float64 `json:"seconds"`
+	// Location is the place a location refers to.
+	Location *Location `json:"location"`
+}
+
+// Location is a place.
+type Location struct {
+	// Name is the full name of the place, e.g. "Los Angeles, CA, USA".
+	Name string `json:"name"`
+	// Latitude and Longitude are the coordinates of the place, in degrees.
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Duration returns the length of a duration.
+func (v *EntityValue) Duration() time.Duration {
+	return time.Duration(v.Seconds * float64(time.Second))
 }
 
 // GetInterpret queries the API with the provided text and returns
@@ -31,7 +125,10 @@ func GetInterpret(c *config.Config, text string) (*InterpretResponse, error) {
 		Credentials: c.GetCredentials(),
 		Method:      "GET",
 		Path:        interpretEndpoint,
-		Query:       url.Values(map[string][]string{"text": []string{text}}),
+		Query: url.Values(map[string][]string{
+			"text":    []string{text},
+			"details": []string{"true"},
+		}),
 	}
 
 	res, err := c.Backend.Call(params)
@@ -41,6 +138,8 @@ func GetInterpret(c *config.Config, text string) (*InterpretResponse, error) {
 
 	defer res.Body.Close()
 	var i InterpretResponse
-	err = json.NewDecoder(res.Body).Decode(&i)
-	return &i, err
+	if err := json.NewDecoder(res.Body).Decode(&i); err != nil {
+		return nil, errors.NewFromErrorCodeInfo(errors.APIMalformedResponse, err.Error())
+	}
+	return &i, nil
 }
@@ -1,7 +1,9 @@
 package api_test
 
 import (
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/auroraapi/aurora-go/api"
 	"github.com/auroraapi/aurora-go/api/backend"
@@ -33,3 +35,61 @@ func TestGetInterpret(t *testing.T) {
 	require.Equal(t, "time", r.Intent)
 	require.Equal(t, "los angeles", r.Entities["location"])
 }
+
+func TestGetInterpretDetails(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"text": "set a timer for ten minutes in los angeles tomorrow",
+			"intent": "timer",
+			"confidence": 0.8,
+			"entities": {"duration": "10 minutes", "location": "los angeles", "date": "2026-10-20"},
+			"intents": [{"intent": "timer", "score": 0.8}, {"intent": "alarm", "score": 0.15}],
+			"entity_details": [
+				{"name": "duration", "type": "duration", "text": "ten minutes", "start": 16, "end": 27, "confidence": 0.9,
+					"value": {"text": "10 minutes", "seconds": 600}},
+				{"name": "location", "type": "location", "text": "los angeles", "start": 31, "end": 42, "confidence": 0.95,
+					"value": {"text": "los angeles", "location": {"name": "Los Angeles, CA, USA", "latitude": 34.05, "longitude": -118.24}}},
+				{"name": "date", "type": "date", "text": "tomorrow", "start": 43, "end": 51, "confidence": 0.7,
+					"value": {"text": "2026-10-20", "time": "2026-10-20T00:00:00-07:00"}}
+			]
+		}`))
+	})
+
+	r, err := api.GetInterpret(stub, "set a timer for ten minutes in los angeles tomorrow")
+	require.Nil(t, err)
+	req := received(t, requests)
+	require.Equal(t, "/v1/interpret/", req.URL.Path)
+	require.Equal(t, "true", req.URL.Query().Get("details"))
+	require.Equal(t, "timer", r.Intent)
+	require.Equal(t, 0.8, r.Confidence)
+	require.Equal(t, "los angeles", r.Entities["location"])
+	require.Equal(t, []*api.InterpretIntent{{Intent: "timer", Score: 0.8}, {Intent: "alarm", Score: 0.15}}, r.Intents)
+
+	require.Len(t, r.EntityDetails, 3)
+	duration := r.EntityDetails[0]
+	require.Equal(t, api.EntityDuration, duration.Type)
+	require.Equal(t, "ten minutes", r.Text[duration.Start:duration.End])
+	require.Equal(t, 10*time.Minute, duration.Value.Duration())
+
+	location := r.EntityDetails[1]
+	require.Equal(t, api.EntityLocation, location.Type)
+	require.Equal(t, &api.Location{Name: "Los Angeles, CA, USA", Latitude: 34.05, Longitude: -118.24}, location.Value.Location)
+
+	date := r.EntityDetails[2]
+	require.Equal(t, api.EntityDate, date.Type)
+	require.Equal(t, 0.7, date.Confidence)
+	require.True(t, time.Date(2026, 10, 20, 7, 0, 0, 0, time.UTC).Equal(date.Value.Time))
+}
+
+func TestGetInterpretMalformed(t *testing.T) {
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>"))
+	})
+
+	_, err := api.GetInterpret(stub, "hello")
+	require.NotNil(t, err)
+	require.Equal(t, errors.APIMalformedResponse, err.(*errors.Error).Code)
+}
@@ -3,32 +3,187 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net/url"
+	"strconv"
 
 	"github.com/auroraapi/aurora-go/api/backend"
 	"github.com/auroraapi/aurora-go/audio"
 	"github.com/auroraapi/aurora-go/config"
+	"github.com/auroraapi/aurora-go/errors"
 )
 
+// DefaultSTTAlternatives is the default number of alternative transcripts
+// requested from the API, including the most likely one.
+const DefaultSTTAlternatives = 3
+
 // STTResponse is the response returned by the API if the speech was
 // successfully able to be transcribed.
 type STTResponse struct {
+	// Transcript is the most likely transcript of the speech.
 	Transcript string `json:"transcript"`
+	// Confidence is how confident the API is in the transcript, from 0 to
+	// 1. It is 0 if the API didn't report a confidence.
+	Confidence float64 `json:"confidence"`
+	// Words are the words of the transcript, with their timings.
+	Words []*STTWord `json:"words"`
+	// Alternatives are the most likely transcripts, in order, starting with
+	// the one in `Transcript`. It may be empty if the API only returned one.
+	Alternatives []*STTAlternative `json:"alternatives"`
+	// Language is the language detected in the speech, as a BCP-47 tag such
+	// as "en-US". It is empty if the API didn't detect one.
+	Language string `json:"language"`
+}
+
+// STTAlternative is one of the possible transcripts of the speech.
+type STTAlternative struct {
+	Transcript string     `json:"transcript"`
+	Confidence float64    `json:"confidence"`
+	Words      []*STTWord `json:"words"`
+}
+
+// STTWord is a word in a transcript.
+type STTWord struct {
+	Word string `json:"word"`
+	// Start and End are the position (in seconds) of the word within the
+	// audio.
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Confidence is how confident the API is in the word, from 0 to 1. It is
+	// 0 if the API didn't report a confidence.
+	Confidence float64 `json:"confidence"`
+}
+
+// STTOptions configure how speech is transcribed. Options left at their zero
+// value aren't sent, so the API's defaults are used: in particular, the
+// formatting options (`ProfanityFilter`, `Punctuation` and `FormatNumbers`)
+// are only sent when they are set to true.
+type STTOptions struct {
+	// Language is the language of the speech, as a BCP-47 tag such as
+	// "en-US" or "fr-CA". If it is empty, the API detects the language.
+	Language string
+	// Hints are words or phrases that are likely to be spoken (such as names
+	// or commands the app understands), which are then more likely to be
+	// recognized.
+	Hints []*STTHint
+	// ProfanityFilter masks profanity in the transcript, e.g. "f***".
+	ProfanityFilter bool
+	// Punctuation adds punctuation and capitalization to the transcript.
+	Punctuation bool
+	// FormatNumbers writes spoken numbers, dates, times, etc. as digits, e.g.
+	// "10:30" instead of "ten thirty".
+	FormatNumbers bool
+	// Model is the name of the recognition model to use, which can be
+	// tuned for e.g. short commands or long dictation. If it is empty, the
+	// API's default model is used.
+	Model string
+	// Alternatives is the maximum number of alternative transcripts to
+	// return, including the most likely one.
+	Alternatives int
+}
+
+// STTHint is a phrase that is likely to be spoken.
+type STTHint struct {
+	Phrase string `json:"phrase"`
+	// Boost is how much more likely the phrase is to be recognized. A value
+	// of 0 uses the API's default boost.
+	Boost float64 `json:"boost,omitempty"`
+}
+
+// NewSTTOptions creates the default set of STTOptions. You should call this
+// function to get the default and then replace the ones you want to
+// customize.
+func NewSTTOptions() *STTOptions {
+	return &STTOptions{
+		Alternatives: DefaultSTTAlternatives,
+	}
+}
+
+// query encodes the options as query parameters for a speech to text
+// request. Word timings are always requested.
+func (o *STTOptions) query() (url.Values, error) {
+	if o == nil {
+		o = NewSTTOptions()
+	}
+	if o.Alternatives < 0 {
+		return nil, errors.NewFromErrorCodeInfo(errors.STTInvalidOptions, fmt.Sprintf("Requested %d alternatives.", o.Alternatives))
+	}
+
+	alternatives := o.Alternatives
+	if alternatives == 0 {
+		alternatives = DefaultSTTAlternatives
+	}
+	query := url.Values{
+		"alternatives": []string{strconv.Itoa(alternatives)},
+		"words":        []string{"true"},
+	}
+	if o.ProfanityFilter {
+		query.Set("profanity_filter", "true")
+	}
+	if o.Punctuation {
+		query.Set("punctuation", "true")
+	}
+	if o.FormatNumbers {
+		query.Set("format_numbers", "true")
+	}
+	if o.Language != "" {
+		query.Set("language", o.Language)
+	}
+	if o.Model != "" {
+		query.Set("model", o.Model)
+	}
+	if len(o.Hints) > 0 {
+		for _, h := range o.Hints {
+			if h.Phrase == "" || h.Boost < 0 {
+				return nil, errors.NewFromErrorCodeInfo(errors.STTInvalidOptions, fmt.Sprintf("Invalid hint %q with boost %g.", h.Phrase, h.Boost))
+			}
+		}
+		// phrases can contain any character, so they are sent as JSON
+		hints, err := json.Marshal(o.Hints)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("hints", string(hints))
+	}
+	return query, nil
 }
 
 // GetSTT queries the API with the provided audio file and returns
-// a transcript of the speech.
+// a transcript of the speech. If the config has a `QualityCheck`, the audio
+// is checked first and unusable audio is rejected without calling the API
+// (unless a `QualityWarning` handler is set).
 func GetSTT(c *config.Config, audio *audio.File) (*STTResponse, error) {
-	return GetSTTFromStream(c, bytes.NewReader(audio.WAVData()))
+	return GetSTTWithOptions(c, audio, nil)
+}
+
+// GetSTTWithOptions is like `GetSTT`, but transcribes the speech with the
+// given options. Pass `nil` to use the defaults.
+func GetSTTWithOptions(c *config.Config, audio *audio.File, options *STTOptions) (*STTResponse, error) {
+	if err := checkQuality(c, audio); err != nil {
+		return nil, err
+	}
+	return GetSTTFromStreamWithOptions(c, bytes.NewReader(audio.WAVData()), options)
 }
 
 // GetSTTFromStream queries the API with the provided raw WAV audio stream
-// and returns a transcript of the speech.
+// and returns a transcript of the speech. Streams are not quality checked.
 func GetSTTFromStream(c *config.Config, audio io.Reader) (*STTResponse, error) {
+	return GetSTTFromStreamWithOptions(c, audio, nil)
+}
+
+// GetSTTFromStreamWithOptions is like `GetSTTFromStream`, but transcribes the
+// speech with the given options. Pass `nil` to use the defaults.
+func GetSTTFromStreamWithOptions(c *config.Config, audio io.Reader, options *STTOptions) (*STTResponse, error) {
+	query, err := options.query()
+	if err != nil {
+		return nil, err
+	}
 	params := &backend.CallParams{
 		Credentials: c.GetCredentials(),
 		Method:      "POST",
 		Path:        sttEndpoint,
+		Query:       query,
 		Body:        audio,
 	}
 
@@ -39,6 +194,26 @@ func GetSTTFromStream(c *config.Config, audio io.Reader) (*STTResponse, error) {
 
 	defer res.Body.Close()
 	var stt STTResponse
-	json.NewDecoder(res.Body).Decode(&stt)
+	if err := json.NewDecoder(res.Body).Decode(&stt); err != nil {
+		return nil, errors.NewFromErrorCodeInfo(errors.APIMalformedResponse, err.Error())
+	}
 	return &stt, nil
 }
+
+// checkQuality runs the config's quality check (if any) on the audio. It
+// returns the first problem found, or passes them all to the warning handler
+// if one is set.
+func checkQuality(c *config.Config, audio *audio.File) error {
+	if c.QualityCheck == nil {
+		return nil
+	}
+	problems := audio.Quality().Problems(c.QualityCheck)
+	if len(problems) == 0 {
+		return nil
+	}
+	if c.QualityWarning != nil {
+		c.QualityWarning(problems)
+		return nil
+	}
+	return problems[0]
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/auroraapi/aurora-go/api/backend"
+	"github.com/auroraapi/aurora-go/config"
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+// sttStreamBufferSize is the number of hypotheses held until they are
+// received from `STTStream.Results`.
+const sttStreamBufferSize = 16
+
+// STTHypothesis is a transcript returned by a streaming speech to text
+// session.
+type STTHypothesis struct {
+	// Transcript is the text recognized so far in the current utterance.
+	Transcript string `json:"transcript"`
+	// Final is false for interim hypotheses, each of which replaces the one
+	// before it as more audio is recognized. It is true once the utterance
+	// is complete and the transcript won't change. Hypotheses after a final
+	// one belong to the next utterance.
+	Final bool `json:"final"`
+}
+
+// STTStream is a streaming speech to text session. Audio is uploaded as it
+// is written, in a single chunked request, while hypotheses are streamed back
+// (as newline-delimited JSON) on the same request. Sending and receiving at
+// the same time needs HTTP/2, which Go's HTTP client negotiates automatically
+// over HTTPS.
+//
+// The session is subject to the timeout of the backend's HTTP client, so
+// set a longer one with `Backend.SetClient` for long sessions.
+type STTStream struct {
+	// Results receives hypotheses as they are recognized. It is closed once
+	// the session is over, after which `Err` reports whether it failed. Once
+	// the stream has been closed, hypotheses that don't fit in its buffer
+	// because it isn't being read are dropped and the session ends, so
+	// stopping reading early doesn't leak the connection.
+	Results <-chan *STTHypothesis
+
+	body      *io.PipeWriter
+	err       error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSTTStream starts a streaming speech to text session. Write a WAV stream
+// (for example, from `audio.NewRecordingStream`) to it, and call `Close` once
+// the audio is finished to receive the final hypothesis.
+func NewSTTStream(c *config.Config) *STTStream {
+	return NewSTTStreamWithOptions(c, nil)
+}
+
+// NewSTTStreamWithOptions is like `NewSTTStream`, but transcribes the speech
+// with the given options. Pass `nil` to use the defaults.
+func NewSTTStreamWithOptions(c *config.Config, options *STTOptions) *STTStream {
+	r, w := io.Pipe()
+	results := make(chan *STTHypothesis, sttStreamBufferSize)
+	s := &STTStream{Results: results, body: w, done: make(chan struct{})}
+
+	go func() {
+		defer close(results)
+		s.err = receiveSTTStream(c, options, r, results, s.done)
+		// stop any further writes, since nobody is listening
+		if s.err != nil {
+			r.CloseWithError(s.err)
+		} else {
+			r.CloseWithError(io.ErrClosedPipe)
+		}
+	}()
+	return s
+}
+
+// StreamSTT starts a streaming speech to text session (see `NewSTTStream`)
+// that uploads the WAV audio from `audio` as it becomes available, and ends
+// the audio when the reader returns EOF. If the reader returns another error,
+// the session fails with it.
+func StreamSTT(c *config.Config, audio io.Reader) *STTStream {
+	s := NewSTTStream(c)
+	go func() {
+		if _, err := io.Copy(s, audio); err != nil {
+			s.CloseWithError(err)
+			return
+		}
+		s.Close()
+	}()
+	return s
+}
+
+// Write uploads more of the WAV stream. It returns an error if the session
+// has ended.
+func (s *STTStream) Write(p []byte) (int, error) {
+	return s.body.Write(p)
+}
+
+// Close marks the end of the audio. Hypotheses continue to be received until
+// the final one, as long as `Results` is read.
+func (s *STTStream) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return s.body.Close()
+}
+
+// CloseWithError abandons the audio, so that the session fails with `err`
+// (for example, because recording failed).
+func (s *STTStream) CloseWithError(err error) error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return s.body.CloseWithError(err)
+}
+
+// Err returns the error that ended the session, or nil if it finished
+// normally. It must only be called once `Results` has been closed.
+func (s *STTStream) Err() error {
+	return s.err
+}
+
+// receiveSTTStream sends the request and passes each hypothesis in the
+// response on to `results`. Once `done` is closed, it stops (closing the
+// response) instead of waiting for room in `results`.
+func receiveSTTStream(c *config.Config, options *STTOptions, body io.Reader, results chan<- *STTHypothesis, done <-chan struct{}) error {
+	query, err := options.query()
+	if err != nil {
+		return err
+	}
+	headers := make(http.Header)
+	headers.Set("Content-Type", "audio/wav")
+	params := &backend.CallParams{
+		Credentials: c.GetCredentials(),
+		Method:      "POST",
+		Path:        sttStreamEndpoint,
+		Headers:     headers,
+		Query:       query,
+		Body:        body,
+	}
+
+	res, err := c.Backend.Call(params)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// errors after the response has started are sent in place of a
+		// hypothesis
+		var apiErr errors.APIError
+		if err := json.Unmarshal(raw, &apiErr); err == nil && apiErr.Code != "" {
+			return &apiErr
+		}
+		var hypothesis STTHypothesis
+		if err := json.Unmarshal(raw, &hypothesis); err != nil {
+			return err
+		}
+		// deliver everything that fits before checking whether the stream
+		// was closed, so that the final hypothesis isn't lost to the race
+		select {
+		case results <- &hypothesis:
+			continue
+		default:
+		}
+		select {
+		case results <- &hypothesis:
+		case <-done:
+			// nobody is reading, so hang up (the deferred Close releases
+			// the response)
+			return nil
+		}
+	}
+}
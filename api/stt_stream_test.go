@@ -0,0 +1,124 @@
+package api_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/auroraapi/aurora-go/api"
+	"github.com/auroraapi/aurora-go/api/backend"
+	"github.com/auroraapi/aurora-go/config"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// streamConfig returns a config that uses a stand-in streaming server, which
+// transcribes audio as the number of bytes received.
+func streamConfig(t *testing.T, appID string) *config.Config {
+	s := testutils.NewSTTStreamServer(func(audio []byte) string {
+		return fmt.Sprintf("%d bytes", len(audio))
+	})
+	t.Cleanup(s.Close)
+	return &config.Config{
+		AppID:    appID,
+		AppToken: "AppToken",
+		Backend:  backend.NewAuroraBackendWithClient(s.URL, s.Client()),
+	}
+}
+
+// Interim hypotheses arrive while audio is still being sent.
+func TestSTTStream(t *testing.T) {
+	s := api.NewSTTStream(streamConfig(t, "AppID"))
+
+	_, err := s.Write(testutils.CreateEmptyWAVFile())
+	require.Nil(t, err)
+	h := <-s.Results
+	require.Equal(t, &api.STTHypothesis{Transcript: "44 bytes"}, h)
+
+	_, err = s.Write(make([]byte, 100))
+	require.Nil(t, err)
+	h = <-s.Results
+	require.Equal(t, &api.STTHypothesis{Transcript: "144 bytes"}, h)
+
+	require.Nil(t, s.Close())
+	h = <-s.Results
+	require.Equal(t, &api.STTHypothesis{Transcript: "144 bytes", Final: true}, h)
+	_, open := <-s.Results
+	require.False(t, open)
+	require.Nil(t, s.Err())
+}
+
+func TestStreamSTT(t *testing.T) {
+	audio := append(testutils.CreateEmptyWAVFile(), make([]byte, 1000)...)
+	s := api.StreamSTT(streamConfig(t, "AppID"), bytes.NewReader(audio))
+
+	var last *api.STTHypothesis
+	for h := range s.Results {
+		last = h
+	}
+	require.Nil(t, s.Err())
+	require.Equal(t, &api.STTHypothesis{Transcript: "1044 bytes", Final: true}, last)
+}
+
+func TestSTTStreamError(t *testing.T) {
+	s := api.NewSTTStream(streamConfig(t, ""))
+	for range s.Results {
+		t.Fatal("no hypotheses should be received")
+	}
+	require.IsType(t, apiErrorType, s.Err())
+	require.Equal(t, "MissingApplicationID", s.Err().(*errors.APIError).Code)
+
+	// the audio can't be sent anywhere
+	_, err := s.Write(make([]byte, 10))
+	require.NotNil(t, err)
+}
+
+// A stream that is closed without reading all of the results doesn't keep the
+// response open.
+func TestSTTStreamAbandoned(t *testing.T) {
+	hungUp, finished := make(chan struct{}), make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// more hypotheses than the stream buffers
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(w, "{\"transcript\": \"%d\"}\n", i)
+		}
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			close(hungUp)
+		case <-finished:
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	defer close(finished)
+
+	s := api.NewSTTStream(&config.Config{
+		AppID:    "AppID",
+		AppToken: "AppToken",
+		Backend:  backend.NewAuroraBackendWithClient(server.URL, server.Client()),
+	})
+	_, err := s.Write(testutils.CreateEmptyWAVFile())
+	require.Nil(t, err)
+	// wait until the stream's buffer is full
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.Results) < cap(s.Results) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, cap(s.Results), len(s.Results))
+	require.Nil(t, s.Close())
+
+	select {
+	case <-hungUp:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the response wasn't closed")
+	}
+	for range s.Results {
+	}
+	require.Nil(t, s.Err())
+}
@@ -1,6 +1,9 @@
 package api_test
 
 import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/auroraapi/aurora-go/api"
@@ -38,3 +41,143 @@ func TestGetSTT(t *testing.T) {
 	require.NotNil(t, r)
 	require.IsType(t, STTResponseType, r)
 }
+
+// stubConfig returns a config whose backend is served by `handler`.
+func stubConfig(t *testing.T, handler http.HandlerFunc) *config.Config {
+	s := httptest.NewServer(handler)
+	t.Cleanup(s.Close)
+	return &config.Config{
+		AppID:    "AppID",
+		AppToken: "AppToken",
+		Backend:  backend.NewAuroraBackendWithClient(s.URL, s.Client()),
+	}
+}
+
+// received returns the next request that a stub handler passed to
+// `requests`. Handlers run on the server's goroutines, where failing the test
+// doesn't stop it, so they hand requests over to be checked by the test.
+func received(t *testing.T, requests <-chan *http.Request) *http.Request {
+	select {
+	case r := <-requests:
+		return r
+	default:
+		t.Fatal("no request was received")
+		return nil
+	}
+}
+
+func TestGetSTTFromStreamDetails(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"transcript": "recognize speech",
+			"confidence": 0.9,
+			"language": "en-US",
+			"words": [
+				{"word": "recognize", "start": 0.1, "end": 0.6, "confidence": 0.95},
+				{"word": "speech", "start": 0.6, "end": 1.0, "confidence": 0.85}
+			],
+			"alternatives": [
+				{"transcript": "recognize speech", "confidence": 0.9},
+				{"transcript": "wreck a nice beach", "confidence": 0.4}
+			]
+		}`))
+	})
+
+	r, err := api.GetSTTFromStream(stub, bytes.NewReader(testutils.CreateEmptyWAVFile()))
+	require.Nil(t, err)
+	q := received(t, requests).URL.Query()
+	require.Equal(t, "3", q.Get("alternatives"))
+	require.Equal(t, "true", q.Get("words"))
+	require.Equal(t, "recognize speech", r.Transcript)
+	require.Equal(t, 0.9, r.Confidence)
+	require.Equal(t, "en-US", r.Language)
+	require.Equal(t, []*api.STTWord{
+		{Word: "recognize", Start: 0.1, End: 0.6, Confidence: 0.95},
+		{Word: "speech", Start: 0.6, End: 1.0, Confidence: 0.85},
+	}, r.Words)
+	require.Len(t, r.Alternatives, 2)
+	require.Equal(t, "wreck a nice beach", r.Alternatives[1].Transcript)
+	require.Equal(t, 0.4, r.Alternatives[1].Confidence)
+}
+
+func TestGetSTTFromStreamMalformed(t *testing.T) {
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>gateway timeout</html>`))
+	})
+
+	_, err := api.GetSTTFromStream(stub, bytes.NewReader(testutils.CreateEmptyWAVFile()))
+	require.NotNil(t, err)
+	require.Equal(t, errors.APIMalformedResponse, err.(*errors.Error).Code)
+}
+
+func TestGetSTTFromStreamOptions(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write([]byte(`{"transcript": "d'accord"}`))
+	})
+
+	options := api.NewSTTOptions()
+	options.Language = "fr-CA"
+	options.Hints = []*api.STTHint{{Phrase: "Aurora", Boost: 5}, {Phrase: "d'accord"}}
+	options.ProfanityFilter = true
+	options.FormatNumbers = true
+	options.Model = "command"
+	options.Alternatives = 1
+	r, err := api.GetSTTFromStreamWithOptions(stub, bytes.NewReader(testutils.CreateEmptyWAVFile()), options)
+	require.Nil(t, err)
+	require.Equal(t, "d'accord", r.Transcript)
+
+	q := received(t, requests).URL.Query()
+	require.Equal(t, "fr-CA", q.Get("language"))
+	require.Equal(t, `[{"phrase":"Aurora","boost":5},{"phrase":"d'accord"}]`, q.Get("hints"))
+	require.Equal(t, "true", q.Get("profanity_filter"))
+	_, punctuation := q["punctuation"]
+	require.False(t, punctuation)
+	require.Equal(t, "true", q.Get("format_numbers"))
+	require.Equal(t, "command", q.Get("model"))
+	require.Equal(t, "1", q.Get("alternatives"))
+}
+
+// Without options, the API's defaults are used for formatting.
+func TestGetSTTFromStreamDefaultOptions(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write([]byte(`{"transcript": "hello"}`))
+	})
+
+	for _, options := range []*api.STTOptions{nil, api.NewSTTOptions()} {
+		r, err := api.GetSTTFromStreamWithOptions(stub, bytes.NewReader(testutils.CreateEmptyWAVFile()), options)
+		require.Nil(t, err)
+		require.Equal(t, "hello", r.Transcript)
+
+		q := received(t, requests).URL.Query()
+		for _, key := range []string{"profanity_filter", "punctuation", "format_numbers", "language", "model", "hints"} {
+			_, ok := q[key]
+			require.False(t, ok, key)
+		}
+		require.Equal(t, "true", q.Get("words"))
+	}
+}
+
+func TestGetSTTFromStreamInvalidOptions(t *testing.T) {
+	requests := make(chan *http.Request, 3)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	})
+
+	for _, options := range []*api.STTOptions{
+		{Alternatives: -1},
+		{Hints: []*api.STTHint{{Phrase: ""}}},
+		{Hints: []*api.STTHint{{Phrase: "Aurora", Boost: -2}}},
+	} {
+		_, err := api.GetSTTFromStreamWithOptions(stub, bytes.NewReader(testutils.CreateEmptyWAVFile()), options)
+		require.NotNil(t, err)
+		require.Equal(t, errors.STTInvalidOptions, err.(*errors.Error).Code)
+	}
+	require.Len(t, requests, 0, "invalid options shouldn't be sent")
+}
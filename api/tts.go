@@ -1,21 +1,284 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/auroraapi/aurora-go/api/backend"
 	"github.com/auroraapi/aurora-go/audio"
 	"github.com/auroraapi/aurora-go/config"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/ssml"
 )
 
+// TTSEncoding is the format of the audio returned by the TTS API.
+type TTSEncoding string
+
+const (
+	// TTSEncodingWAV is 16-bit PCM audio in a WAV file (the default).
+	TTSEncodingWAV TTSEncoding = "wav"
+	// TTSEncodingPCM is raw 16-bit little-endian PCM audio, without a
+	// header.
+	TTSEncodingPCM TTSEncoding = "pcm"
+	// TTSEncodingMP3 is MP3 compressed audio.
+	TTSEncodingMP3 TTSEncoding = "mp3"
+	// TTSEncodingOpus is Opus compressed audio in an Ogg container.
+	TTSEncodingOpus TTSEncoding = "ogg_opus"
+)
+
+// TTS option limits.
+const (
+	// MinTTSRate and MaxTTSRate are the slowest and fastest speaking rates.
+	MinTTSRate = 0.25
+	MaxTTSRate = 4.0
+	// MaxTTSPitch is the largest pitch shift (in semitones) in either
+	// direction.
+	MaxTTSPitch = 24.0
+)
+
+// maxTTSQueryLength is the longest (encoded) query string sent in a GET
+// request. Longer requests are sent as a POST, since many servers and proxies
+// reject long URLs.
+const maxTTSQueryLength = 2000
+
+// TTSOptions configure how text is converted to speech. The zero value of
+// each option means the API's default.
+type TTSOptions struct {
+	// Voice is the name of the voice to speak with (see `GetVoices`).
+	Voice string
+	// Rate is the speaking rate relative to normal, e.g. 1.5 to speak 50%
+	// faster. It must be between `MinTTSRate` and `MaxTTSRate`.
+	Rate float64
+	// Pitch shifts the pitch of the voice by this many semitones, up to
+	// `MaxTTSPitch` in either direction.
+	Pitch float64
+	// Volume changes the volume of the voice by this many decibels.
+	Volume float64
+	// SampleRate is the sample rate (in Hz) of the audio. It must be set for
+	// PCM audio that is returned as an `audio.File`, since raw audio doesn't
+	// say what rate it has.
+	SampleRate uint32
+	// Encoding is the format of the audio. Only WAV and PCM audio can be
+	// returned as an `audio.File`; use `GetTTSData` for the others.
+	Encoding TTSEncoding
+	// SSML is true if the text is an SSML document (see the `ssml` package)
+	// rather than plain text.
+	SSML bool
+}
+
+// Voice is a voice that text can be spoken with.
+type Voice struct {
+	// Name identifies the voice in `TTSOptions.Voice`.
+	Name string `json:"name"`
+	// Language is the language the voice speaks, as a BCP-47 tag such as
+	// "en-US".
+	Language string `json:"language"`
+	// Gender is the gender of the voice ("female", "male" or "neutral").
+	Gender string `json:"gender"`
+}
+
+// TTSTimepointType is the kind of position reported by a timepoint.
+type TTSTimepointType string
+
+const (
+	// TTSTimepointWord is a word of the text.
+	TTSTimepointWord TTSTimepointType = "word"
+	// TTSTimepointMark is a mark in an SSML document (see `ssml.Mark`).
+	TTSTimepointMark TTSTimepointType = "mark"
+)
+
+// TTSTimepoint is when a word or mark is reached in synthesized speech, e.g.
+// to highlight each word as it is spoken.
+type TTSTimepoint struct {
+	// Type is whether this is a word or a mark.
+	Type TTSTimepointType `json:"type"`
+	// Text is the word, or the name of the mark.
+	Text string `json:"text"`
+	// Offset is the position (in bytes) of the word in the text that was
+	// synthesized. It is 0 for marks.
+	Offset int `json:"offset"`
+	// Start and End are when the word starts and ends (in seconds from the
+	// start of the audio). They are the same for marks.
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// TTSResponse is synthesized speech, with the timepoints of each word and
+// mark in order.
+type TTSResponse struct {
+	Audio      *audio.File
+	Timepoints []*TTSTimepoint
+}
+
+// query encodes the options as query parameters for a TTS request.
+func (o *TTSOptions) query(text string) (url.Values, error) {
+	query := url.Values{"text": []string{text}}
+	if o == nil {
+		return query, nil
+	}
+
+	if o.Rate != 0 && (o.Rate < MinTTSRate || o.Rate > MaxTTSRate) {
+		return nil, errors.NewFromErrorCodeInfo(errors.TTSInvalidOptions, fmt.Sprintf("Requested a rate of %g.", o.Rate))
+	}
+	if o.Pitch < -MaxTTSPitch || o.Pitch > MaxTTSPitch {
+		return nil, errors.NewFromErrorCodeInfo(errors.TTSInvalidOptions, fmt.Sprintf("Requested a pitch shift of %g semitones.", o.Pitch))
+	}
+
+	if o.Voice != "" {
+		query.Set("voice", o.Voice)
+	}
+	if o.Rate != 0 {
+		query.Set("rate", strconv.FormatFloat(o.Rate, 'g', -1, 64))
+	}
+	if o.Pitch != 0 {
+		query.Set("pitch", strconv.FormatFloat(o.Pitch, 'g', -1, 64))
+	}
+	if o.Volume != 0 {
+		query.Set("volume", strconv.FormatFloat(o.Volume, 'g', -1, 64))
+	}
+	if o.SampleRate != 0 {
+		query.Set("sample_rate", strconv.FormatUint(uint64(o.SampleRate), 10))
+	}
+	if o.Encoding != "" {
+		query.Set("encoding", string(o.Encoding))
+	}
+	if o.SSML {
+		if err := ssml.Check(text); err != nil {
+			return nil, err
+		}
+		query.Set("text_type", "ssml")
+	}
+	return query, nil
+}
+
 // GetTTS calls the TTS API given some text and returns an *audio.File
 // with the audio from converting the text to speech.
 func GetTTS(c *config.Config, text string) (*audio.File, error) {
+	return GetTTSWithOptions(c, text, nil)
+}
+
+// GetTTSWithOptions is like `GetTTS`, but speaks the text with the given
+// options. Pass `nil` to use the defaults. The encoding must be WAV or PCM.
+func GetTTSWithOptions(c *config.Config, text string, options *TTSOptions) (*audio.File, error) {
+	if err := options.checkDecodable(); err != nil {
+		return nil, err
+	}
+	data, err := GetTTSData(c, text, options)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTTSAudio(data, options)
+}
+
+// GetTTSWithTimepoints is like `GetTTSWithOptions`, but also returns when
+// each word of the text, and each mark in an SSML document, is spoken.
+func GetTTSWithTimepoints(c *config.Config, text string, options *TTSOptions) (*TTSResponse, error) {
+	if err := options.checkDecodable(); err != nil {
+		return nil, err
+	}
+	query, err := options.query(text)
+	if err != nil {
+		return nil, err
+	}
+	query.Set("timepoints", "true")
+	res, err := callTTSWithQuery(c, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	var body struct {
+		Audio      []byte          `json:"audio"`
+		Timepoints []*TTSTimepoint `json:"timepoints"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, errors.NewFromErrorCodeInfo(errors.APIMalformedResponse, err.Error())
+	}
+	f, err := decodeTTSAudio(body.Audio, options)
+	if err != nil {
+		return nil, err
+	}
+	return &TTSResponse{Audio: f, Timepoints: body.Timepoints}, nil
+}
+
+// checkDecodable checks that the audio can be returned as an `audio.File`.
+func (o *TTSOptions) checkDecodable() error {
+	if o != nil && o.Encoding != "" && o.Encoding != TTSEncodingWAV && o.Encoding != TTSEncodingPCM {
+		return errors.NewFromErrorCodeInfo(errors.TTSInvalidOptions, fmt.Sprintf("%q audio can't be decoded. Use GetTTSData instead.", o.Encoding))
+	}
+	if o != nil && o.Encoding == TTSEncodingPCM && o.SampleRate == 0 {
+		return errors.NewFromErrorCodeInfo(errors.TTSInvalidOptions, "PCM audio can't be decoded without knowing its sample rate. Set SampleRate in the options.")
+	}
+	return nil
+}
+
+// decodeTTSAudio converts WAV or PCM audio returned by the TTS API to a file.
+func decodeTTSAudio(data []byte, options *TTSOptions) (*audio.File, error) {
+	if options == nil || options.Encoding != TTSEncodingPCM {
+		return audio.NewFileFromBytes(data)
+	}
+
+	// raw audio is in the requested format
+	return &audio.File{
+		AudioData: audio.NewWAVFromParams(&audio.WAVParams{
+			SampleRate: options.SampleRate,
+			AudioData:  data,
+		}),
+	}, nil
+}
+
+// GetTTSData calls the TTS API given some text and returns the audio in the
+// encoding given by the options, e.g. to save an MP3 file. Pass `nil` to use
+// the defaults (a WAV file).
+func GetTTSData(c *config.Config, text string, options *TTSOptions) ([]byte, error) {
+	res, err := callTTS(c, text, options)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	return ioutil.ReadAll(res.Body)
+}
+
+// callTTS makes a TTS request and returns the response, whose body is the
+// audio.
+func callTTS(c *config.Config, text string, options *TTSOptions) (*http.Response, error) {
+	query, err := options.query(text)
+	if err != nil {
+		return nil, err
+	}
+	return callTTSWithQuery(c, query)
+}
+
+// callTTSWithQuery makes a TTS request with the given parameters.
+func callTTSWithQuery(c *config.Config, query url.Values) (*http.Response, error) {
 	params := &backend.CallParams{
 		Credentials: c.GetCredentials(),
 		Method:      "GET",
 		Path:        ttsEndpoint,
-		Query:       url.Values(map[string][]string{"text": []string{text}}),
+		Query:       query,
+	}
+	if encoded := query.Encode(); len(encoded) > maxTTSQueryLength {
+		// too long for a URL, so send the parameters as a form instead
+		params.Method = "POST"
+		params.Query = url.Values{}
+		params.Body = strings.NewReader(encoded)
+		params.Headers = http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}}
+	}
+	return c.Backend.Call(params)
+}
+
+// GetVoices returns the voices that text can be spoken with.
+func GetVoices(c *config.Config) ([]*Voice, error) {
+	params := &backend.CallParams{
+		Credentials: c.GetCredentials(),
+		Method:      "GET",
+		Path:        ttsVoicesEndpoint,
 	}
 
 	res, err := c.Backend.Call(params)
@@ -24,5 +287,11 @@ func GetTTS(c *config.Config, text string) (*audio.File, error) {
 	}
 
 	defer res.Body.Close()
-	return audio.NewFileFromReader(res.Body)
+	var voices struct {
+		Voices []*Voice `json:"voices"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&voices); err != nil {
+		return nil, errors.NewFromErrorCodeInfo(errors.APIMalformedResponse, err.Error())
+	}
+	return voices.Voices, nil
 }
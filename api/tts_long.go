@@ -0,0 +1,250 @@
+package api
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/config"
+)
+
+const (
+	// DefaultLongTTSChunkLength is the default maximum length (in
+	// characters) of the text synthesized by each request.
+	DefaultLongTTSChunkLength = 500
+	// DefaultLongTTSConcurrency is the default number of requests made at
+	// the same time.
+	DefaultLongTTSConcurrency = 4
+	// DefaultLongTTSPause is the default pause (in seconds) between
+	// sentences.
+	DefaultLongTTSPause = 0.3
+)
+
+// LongTTSParams configure how `GetLongTTS` splits up and synthesizes text.
+type LongTTSParams struct {
+	// ChunkLength is the maximum length (in characters) of the text in each
+	// request. Sentences longer than this are split between clauses, or
+	// between words if a clause is too long.
+	ChunkLength int
+	// Concurrency is the maximum number of requests made at the same time.
+	Concurrency int
+	// Pause is the silence (in seconds) inserted between sentences. Parts
+	// of a sentence that was split are joined without a pause.
+	Pause float64
+}
+
+// NewLongTTSParams returns the default long-form synthesis parameters.
+func NewLongTTSParams() *LongTTSParams {
+	return &LongTTSParams{
+		ChunkLength: DefaultLongTTSChunkLength,
+		Concurrency: DefaultLongTTSConcurrency,
+		Pause:       DefaultLongTTSPause,
+	}
+}
+
+// ttsChunk is a piece of text synthesized by a single request.
+type ttsChunk struct {
+	text string
+	// endsSentence is true if the chunk is the end of a sentence, so it is
+	// followed by a pause.
+	endsSentence bool
+}
+
+// GetLongTTS converts text of any length to speech, such as an article or a
+// chapter of a book. The text is split into sentences, which are synthesized
+// concurrently with the given options and joined, in order, into a single
+// audio file. Pass `nil` options or params to use the defaults. SSML
+// documents can't be split, so they are synthesized by a single request.
+func GetLongTTS(c *config.Config, text string, options *TTSOptions, params *LongTTSParams) (*audio.File, error) {
+	if params == nil {
+		params = NewLongTTSParams()
+	}
+	chunks := splitTTSText(text, params.ChunkLength)
+	if len(chunks) == 0 || (options != nil && options.SSML) {
+		// let the API report that there's nothing to say
+		return GetTTSWithOptions(c, text, options)
+	}
+	concurrency := params.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	files := make([]*audio.File, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+	for i, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk ttsChunk) {
+			defer func() { <-sem; wg.Done() }()
+			mu.Lock()
+			skip := failed
+			mu.Unlock()
+			if skip {
+				return
+			}
+			files[i], errs[i] = GetTTSWithOptions(c, chunk.text, options)
+			if errs[i] != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	first := files[0]
+	rest := make([]*audio.File, 0, 2*len(files))
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if chunk.endsSentence && params.Pause > 0 {
+			rest = append(rest, silence(first, params.Pause))
+		}
+		rest = append(rest, files[i+1])
+	}
+	return first.Concat(rest...)
+}
+
+// silence returns `seconds` of silence in the format of `f`.
+func silence(f *audio.File, seconds float64) *audio.File {
+	frameSize := int(f.AudioData.NumChannels) * int(f.AudioData.BitsPerSample/8)
+	frames := int(seconds * float64(f.AudioData.SampleRate))
+	return &audio.File{
+		AudioData: audio.NewWAVFromParams(&audio.WAVParams{
+			NumChannels:   f.AudioData.NumChannels,
+			SampleRate:    f.AudioData.SampleRate,
+			BitsPerSample: f.AudioData.BitsPerSample,
+			AudioData:     make([]byte, frames*frameSize),
+		}),
+	}
+}
+
+// splitTTSText splits text into sentences, splitting sentences longer than
+// `maxLen` characters into chunks of whole clauses (or words, or characters,
+// if those are too long).
+func splitTTSText(text string, maxLen int) []ttsChunk {
+	if maxLen <= 0 {
+		maxLen = DefaultLongTTSChunkLength
+	}
+	chunks := make([]ttsChunk, 0)
+	for _, sentence := range splitSentences(text) {
+		parts := pack(splitClauses(sentence), maxLen)
+		for i, part := range parts {
+			chunks = append(chunks, ttsChunk{text: part, endsSentence: i == len(parts)-1})
+		}
+	}
+	return chunks
+}
+
+// splitSentences splits text after sentence-ending punctuation (and any
+// closing quotes or brackets) that is followed by a space and a character
+// that isn't lowercase, so that abbreviations such as "e.g. this" aren't
+// split. Line breaks also end sentences.
+func splitSentences(text string) []string {
+	runes := []rune(text)
+	sentences := make([]string, 0)
+	start := 0
+	add := func(end int) {
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = end
+	}
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\n' {
+			add(i + 1)
+			continue
+		}
+		if !strings.ContainsRune(".!?…", runes[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && strings.ContainsRune(`"')]”’`, runes[end]) {
+			end++
+		}
+		next := end
+		for next < len(runes) && runes[next] != '\n' && unicode.IsSpace(runes[next]) {
+			next++
+		}
+		if next == end && end < len(runes) {
+			// no space, e.g. "3.5" or "..."
+			continue
+		}
+		if next < len(runes) && unicode.IsLower(runes[next]) {
+			continue
+		}
+		add(end)
+		i = end - 1
+	}
+	add(len(runes))
+	return sentences
+}
+
+// splitClauses splits a sentence after clause-separating punctuation that is
+// followed by a space. The separators are kept with the preceding clause.
+func splitClauses(sentence string) []string {
+	clauses := make([]string, 0)
+	runes := []rune(sentence)
+	start := 0
+	for i := 0; i < len(runes)-1; i++ {
+		if strings.ContainsRune(",;:—–", runes[i]) && unicode.IsSpace(runes[i+1]) {
+			clauses = append(clauses, string(runes[start:i+1]))
+			start = i + 1
+		}
+	}
+	return append(clauses, string(runes[start:]))
+}
+
+// pack joins consecutive pieces into chunks of at most `maxLen` characters,
+// splitting pieces that are too long between words, and words that are too
+// long anywhere.
+func pack(pieces []string, maxLen int) []string {
+	chunks := make([]string, 0)
+	current := ""
+	flush := func() {
+		if s := strings.TrimSpace(current); s != "" {
+			chunks = append(chunks, s)
+		}
+		current = ""
+	}
+	for _, piece := range pieces {
+		if runeLen(current+piece) <= maxLen {
+			current += piece
+			continue
+		}
+		flush()
+		if runeLen(strings.TrimSpace(piece)) <= maxLen {
+			current = piece
+			continue
+		}
+		// the piece is too long on its own
+		for _, word := range strings.Fields(piece) {
+			for runeLen(word) > maxLen {
+				flush()
+				w := []rune(word)
+				chunks = append(chunks, string(w[:maxLen]))
+				word = string(w[maxLen:])
+			}
+			if runeLen(current+" "+word) > maxLen {
+				flush()
+			}
+			current += " " + word
+		}
+	}
+	flush()
+	return chunks
+}
+
+// runeLen returns the number of characters in `s`, ignoring surrounding
+// space.
+func runeLen(s string) int {
+	return len([]rune(strings.TrimSpace(s)))
+}
@@ -0,0 +1,149 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/auroraapi/aurora-go/api"
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/config"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// ttsRecorder records the texts requested from a stub TTS API, and the most
+// requests it received at the same time.
+type ttsRecorder struct {
+	mu        sync.Mutex
+	texts     []string
+	active    int32
+	maxActive int32
+}
+
+// longTTSServer stubs a TTS API that speaks each text as a tenth of a second
+// of a constant sample, given by `sample`.
+func longTTSServer(t *testing.T, sample func(text string) int16) (*config.Config, *ttsRecorder) {
+	rec := &ttsRecorder{}
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&rec.active, 1)
+		defer atomic.AddInt32(&rec.active, -1)
+		for {
+			m := atomic.LoadInt32(&rec.maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&rec.maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		text := r.FormValue("text")
+		rec.mu.Lock()
+		rec.texts = append(rec.texts, text)
+		rec.mu.Unlock()
+
+		v := sample(text)
+		data := make([]byte, 3200)
+		for i := 0; i < len(data); i += 2 {
+			data[i], data[i+1] = byte(v), byte(v>>8)
+		}
+		w.Write(audio.NewWAVFromParams(&audio.WAVParams{AudioData: data}).Data())
+	})
+	return stub, rec
+}
+
+func TestGetLongTTS(t *testing.T) {
+	sentences := []string{
+		"Once upon a time, there was a princess.",
+		"She lived in a castle, e.g. a big house!",
+		"The end?",
+	}
+	index := func(text string) int16 {
+		for i, s := range sentences {
+			if s == text {
+				return int16(i + 1)
+			}
+		}
+		return -1
+	}
+	stub, rec := longTTSServer(t, index)
+
+	f, err := api.GetLongTTS(stub, strings.Join(sentences, "  "), nil, &api.LongTTSParams{
+		ChunkLength: 100,
+		Concurrency: 2,
+		Pause:       0.05,
+	})
+	require.Nil(t, err)
+	require.ElementsMatch(t, sentences, rec.texts)
+	require.True(t, rec.maxActive <= 2)
+
+	// each sentence in order, separated by pauses
+	samples := f.AudioData.Samples()[0]
+	require.Len(t, samples, 3*1600+2*800)
+	expected := make([]float64, 0)
+	for i := range sentences {
+		if i > 0 {
+			expected = append(expected, make([]float64, 800)...)
+		}
+		for j := 0; j < 1600; j++ {
+			expected = append(expected, float64(i+1)/32768)
+		}
+	}
+	require.InDeltaSlice(t, expected, samples, 1e-9)
+}
+
+// Long sentences are split between clauses, then words, without pauses.
+func TestGetLongTTSSplitsSentences(t *testing.T) {
+	stub, rec := longTTSServer(t, func(string) int16 { return 1 })
+
+	text := "This clause is tiny, but this one goes on and on for a while; and a tiny bit. Supercalifragilistic"
+	f, err := api.GetLongTTS(stub, text, nil, &api.LongTTSParams{ChunkLength: 20, Concurrency: 1, Pause: 0.05})
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"This clause is tiny,",
+		"but this one goes on",
+		"and on for a while;",
+		"and a tiny bit.",
+		"Supercalifragilistic",
+	}, rec.texts)
+	// a pause only after the first sentence
+	require.Len(t, f.AudioData.Samples()[0], 5*1600+800)
+}
+
+func TestGetLongTTSError(t *testing.T) {
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.FormValue("text"), "Bad") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(audio.NewWAVFromParams(&audio.WAVParams{AudioData: make([]byte, 100)}).Data())
+	})
+
+	_, err := api.GetLongTTS(stub, "Good. Bad. Good.", nil, nil)
+	require.NotNil(t, err)
+	require.Equal(t, "UnknownError", err.(*errors.Error).Code)
+}
+
+// Text that's too long for a URL is sent in the body.
+func TestGetTTSLongText(t *testing.T) {
+	text := strings.Repeat("word ", 1000)
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		// the body can't be read once the handler returns
+		r.ParseForm()
+		requests <- r
+		w.Write(audio.NewWAVFromParams(&audio.WAVParams{AudioData: make([]byte, 100)}).Data())
+	})
+
+	_, err := api.GetTTSWithOptions(stub, text, &api.TTSOptions{Voice: "amy"})
+	require.Nil(t, err)
+
+	r := received(t, requests)
+	require.Equal(t, "POST", r.Method)
+	require.Equal(t, "/v1/tts/", r.URL.Path)
+	require.Empty(t, r.URL.RawQuery)
+	require.Equal(t, text, r.PostForm.Get("text"))
+	require.Equal(t, "amy", r.PostForm.Get("voice"))
+}
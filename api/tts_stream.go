@@ -0,0 +1,66 @@
+package api
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/auroraapi/aurora-go/config"
+)
+
+// TTSStream is the audio of a TTS request, read as it is synthesized. The
+// API sends the audio in chunks as soon as each part of the text is spoken,
+// so playback can start long before the whole text has been synthesized.
+//
+// Reading the stream is subject to the timeout of the backend's HTTP client,
+// so set a longer one with `Backend.SetClient` for very long texts.
+type TTSStream struct {
+	body  io.ReadCloser
+	start time.Time
+
+	mu    sync.Mutex
+	first time.Time
+}
+
+// StreamTTS calls the TTS API given some text and returns the audio as a
+// stream. It returns once the API has accepted the request, and the audio can
+// then be read (for example, copied to a file or played with
+// `audio.PlayStream`) as it arrives. Pass `nil` options to use the defaults.
+// The stream must be closed once it has been read.
+func StreamTTS(c *config.Config, text string, options *TTSOptions) (*TTSStream, error) {
+	start := time.Now()
+	res, err := callTTS(c, text, options)
+	if err != nil {
+		return nil, err
+	}
+	return &TTSStream{body: res.Body, start: start}, nil
+}
+
+// Read reads the audio received so far, waiting for more if there isn't any.
+func (s *TTSStream) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	if n > 0 {
+		s.mu.Lock()
+		if s.first.IsZero() {
+			s.first = time.Now()
+		}
+		s.mu.Unlock()
+	}
+	return n, err
+}
+
+// Close stops receiving the audio.
+func (s *TTSStream) Close() error {
+	return s.body.Close()
+}
+
+// TimeToFirstAudio is the time from making the request until the first audio
+// was read, or 0 if none has been read yet.
+func (s *TTSStream) TimeToFirstAudio() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.first.IsZero() {
+		return 0
+	}
+	return s.first.Sub(s.start)
+}
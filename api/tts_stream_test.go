@@ -0,0 +1,62 @@
+package api_test
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/auroraapi/aurora-go/api"
+	"github.com/auroraapi/aurora-go/api/backend"
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/config"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// ttsAudio is a second of silence as a WAV file.
+func ttsAudio() []byte {
+	return audio.NewWAVFromParams(&audio.WAVParams{AudioData: make([]byte, 32000)}).Data()
+}
+
+func ttsStreamConfig(t *testing.T, appID string, data []byte, delay time.Duration) *config.Config {
+	s := testutils.NewTTSStreamServer(data, 4000, delay)
+	t.Cleanup(s.Close)
+	return &config.Config{
+		AppID:    appID,
+		AppToken: "AppToken",
+		Backend:  backend.NewAuroraBackendWithClient(s.URL, s.Client()),
+	}
+}
+
+// The first audio can be read long before the rest has been synthesized.
+func TestStreamTTS(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	data := ttsAudio()
+	s, err := api.StreamTTS(ttsStreamConfig(t, "AppID", data, delay), "hello", nil)
+	require.Nil(t, err)
+	defer s.Close()
+	require.Zero(t, s.TimeToFirstAudio())
+
+	first := make([]byte, 4000)
+	_, err = io.ReadFull(s, first)
+	require.Nil(t, err)
+	require.Equal(t, data[:4000], first)
+	ttfa := s.TimeToFirstAudio()
+	require.True(t, ttfa > 0 && ttfa < delay, "time to first audio was %s", ttfa)
+
+	start := time.Now()
+	rest, err := ioutil.ReadAll(s)
+	require.Nil(t, err)
+	require.Equal(t, data, append(first, rest...))
+	// the remaining chunks were still being synthesized
+	require.True(t, time.Since(start) >= 7*delay)
+	require.Equal(t, ttfa, s.TimeToFirstAudio())
+}
+
+func TestStreamTTSError(t *testing.T) {
+	_, err := api.StreamTTS(ttsStreamConfig(t, "", ttsAudio(), 0), "hello", nil)
+	require.NotNil(t, err)
+	require.Equal(t, "MissingApplicationID", err.(*errors.APIError).Code)
+}
@@ -1,12 +1,16 @@
 package api_test
 
 import (
+	"encoding/json"
+	"net/http"
 	"testing"
 
 	"github.com/auroraapi/aurora-go/api"
 	"github.com/auroraapi/aurora-go/api/backend"
+	"github.com/auroraapi/aurora-go/audio"
 	"github.com/auroraapi/aurora-go/config"
 	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,3 +36,183 @@ func TestGetTTS(t *testing.T) {
 	require.NotNil(t, r)
 	require.IsType(t, audioFileType, r)
 }
+
+func TestGetTTSWithOptions(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write(make([]byte, 4410))
+	})
+
+	f, err := api.GetTTSWithOptions(stub, "hello", &api.TTSOptions{
+		Voice:      "amy",
+		Rate:       1.25,
+		Pitch:      -2,
+		Volume:     3,
+		SampleRate: 22050,
+		Encoding:   api.TTSEncodingPCM,
+	})
+	require.Nil(t, err)
+	require.Equal(t, uint32(22050), f.AudioData.SampleRate)
+	require.InDelta(t, 0.1, f.Duration(), 1e-9)
+
+	r := received(t, requests)
+	q := r.URL.Query()
+	require.Equal(t, "/v1/tts/", r.URL.Path)
+	require.Equal(t, "hello", q.Get("text"))
+	require.Equal(t, "amy", q.Get("voice"))
+	require.Equal(t, "1.25", q.Get("rate"))
+	require.Equal(t, "-2", q.Get("pitch"))
+	require.Equal(t, "3", q.Get("volume"))
+	require.Equal(t, "22050", q.Get("sample_rate"))
+	require.Equal(t, "pcm", q.Get("encoding"))
+}
+
+func TestGetTTSDefaults(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write(testutils.CreateEmptyWAVFile())
+	})
+
+	f, err := api.GetTTS(stub, "hello")
+	require.Nil(t, err)
+	require.Equal(t, uint32(44100), f.AudioData.SampleRate)
+	q := received(t, requests).URL.Query()
+	require.Equal(t, "hello", q.Get("text"))
+	require.Len(t, q, 1)
+}
+
+func TestGetTTSData(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write([]byte("ID3 mp3 data"))
+	})
+
+	options := &api.TTSOptions{Encoding: api.TTSEncodingMP3}
+	data, err := api.GetTTSData(stub, "hello", options)
+	require.Nil(t, err)
+	require.Equal(t, []byte("ID3 mp3 data"), data)
+	require.Equal(t, "mp3", received(t, requests).URL.Query().Get("encoding"))
+
+	// compressed audio can't be returned as a file
+	_, err = api.GetTTSWithOptions(stub, "hello", options)
+	require.NotNil(t, err)
+	require.Equal(t, errors.TTSInvalidOptions, err.(*errors.Error).Code)
+	require.Len(t, requests, 0)
+}
+
+// Raw audio can only be returned as a file if its sample rate is known.
+func TestGetTTSPCMWithoutSampleRate(t *testing.T) {
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 4410))
+	})
+
+	options := &api.TTSOptions{Encoding: api.TTSEncodingPCM}
+	_, err := api.GetTTSWithOptions(stub, "hello", options)
+	require.NotNil(t, err)
+	require.Equal(t, errors.TTSInvalidOptions, err.(*errors.Error).Code)
+	_, err = api.GetTTSWithTimepoints(stub, "hello", options)
+	require.NotNil(t, err)
+	require.Equal(t, errors.TTSInvalidOptions, err.(*errors.Error).Code)
+
+	// the raw audio can still be fetched
+	data, err := api.GetTTSData(stub, "hello", options)
+	require.Nil(t, err)
+	require.Len(t, data, 4410)
+}
+
+func TestGetTTSInvalidOptions(t *testing.T) {
+	requests := make(chan *http.Request, 4)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	})
+
+	for _, options := range []*api.TTSOptions{{Rate: 0.1}, {Rate: 5}, {Pitch: 25}, {Pitch: -30}} {
+		_, err := api.GetTTSData(stub, "hello", options)
+		require.NotNil(t, err)
+		require.Equal(t, errors.TTSInvalidOptions, err.(*errors.Error).Code)
+	}
+	require.Len(t, requests, 0, "invalid options shouldn't be sent")
+}
+
+func TestGetTTSSSML(t *testing.T) {
+	doc := `<speak>Hello <break time="500ms"/> world</speak>`
+	requests := make(chan *http.Request, 2)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write(testutils.CreateEmptyWAVFile())
+	})
+
+	_, err := api.GetTTSWithOptions(stub, doc, &api.TTSOptions{SSML: true})
+	require.Nil(t, err)
+	q := received(t, requests).URL.Query()
+	require.Equal(t, doc, q.Get("text"))
+	require.Equal(t, "ssml", q.Get("text_type"))
+
+	// malformed documents aren't sent
+	_, err = api.GetTTSWithOptions(stub, "<speak>Hello", &api.TTSOptions{SSML: true})
+	require.NotNil(t, err)
+	require.Equal(t, errors.SSMLInvalid, err.(*errors.Error).Code)
+	require.Len(t, requests, 0)
+}
+
+func TestGetTTSWithTimepoints(t *testing.T) {
+	wav := audio.NewWAVFromParams(&audio.WAVParams{AudioData: make([]byte, 320)}).Data()
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"audio": wav,
+			"timepoints": []map[string]interface{}{
+				{"type": "word", "text": "hello", "offset": 7, "start": 0.1, "end": 0.4},
+				{"type": "mark", "text": "middle", "start": 0.45, "end": 0.45},
+				{"type": "word", "text": "world", "offset": 31, "start": 0.5, "end": 0.9},
+			},
+		})
+	})
+
+	doc := `<speak>hello<mark name="middle"/> world</speak>`
+	r, err := api.GetTTSWithTimepoints(stub, doc, &api.TTSOptions{Voice: "amy", SSML: true})
+	require.Nil(t, err)
+	q := received(t, requests).URL.Query()
+	require.Equal(t, "true", q.Get("timepoints"))
+	require.Equal(t, "amy", q.Get("voice"))
+	require.Equal(t, wav, r.Audio.WAVData())
+	require.Equal(t, []*api.TTSTimepoint{
+		{Type: api.TTSTimepointWord, Text: "hello", Offset: 7, Start: 0.1, End: 0.4},
+		{Type: api.TTSTimepointMark, Text: "middle", Start: 0.45, End: 0.45},
+		{Type: api.TTSTimepointWord, Text: "world", Offset: 31, Start: 0.5, End: 0.9},
+	}, r.Timepoints)
+}
+
+func TestGetTTSWithTimepointsMalformed(t *testing.T) {
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testutils.CreateEmptyWAVFile())
+	})
+
+	_, err := api.GetTTSWithTimepoints(stub, "hello", nil)
+	require.NotNil(t, err)
+	require.Equal(t, errors.APIMalformedResponse, err.(*errors.Error).Code)
+}
+
+func TestGetVoices(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	stub := stubConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write([]byte(`{"voices": [
+			{"name": "amy", "language": "en-GB", "gender": "female"},
+			{"name": "miguel", "language": "es-US", "gender": "male"}
+		]}`))
+	})
+
+	voices, err := api.GetVoices(stub)
+	require.Nil(t, err)
+	require.Equal(t, "/v1/tts/voices/", received(t, requests).URL.Path)
+	require.Equal(t, []*api.Voice{
+		{Name: "amy", Language: "en-GB", Gender: "female"},
+		{Name: "miguel", Language: "es-US", Gender: "male"},
+	}, voices)
+}
@@ -0,0 +1,256 @@
+package audio
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultEchoTailLen is the default length (in seconds) of the echo path
+	// that the echo canceller models. Echoes that arrive later than this after
+	// the reference signal was played will not be cancelled.
+	DefaultEchoTailLen = 0.128
+	// DefaultEchoStepSize is the default adaptation rate of the echo canceller.
+	// Values closer to 1 converge faster but are noisier.
+	DefaultEchoStepSize = 0.5
+
+	// echoDoubleTalkThresh is the threshold used by the Geigel double-talk
+	// detector. If the microphone signal exceeds this fraction of the recent
+	// reference peak, the user is assumed to be talking and the filter stops
+	// adapting so that it doesn't learn to cancel the user's voice.
+	echoDoubleTalkThresh = 0.5
+	// echoMaxPending is the maximum amount of reference audio (in seconds)
+	// that is buffered while waiting for microphone data. Anything older is
+	// dropped, since it can no longer be aligned with the echo.
+	echoMaxPending = 1.0
+)
+
+// EchoCanceller is an acoustic echo canceller. It uses a normalised
+// least-mean-squares (NLMS) adaptive filter to learn the path between the
+// speaker and the microphone, and subtracts the estimated echo of the
+// reference (playback) signal from the microphone signal.
+//
+// An EchoCanceller is a Processor, so it can be inserted into the recording
+// pipeline. In that case, the reference signal is supplied by calling
+// `AddReference` or, more commonly, by calling `AttachPlayback`, which
+// automatically forwards everything played with `File.Play`.
+type EchoCanceller struct {
+	// StepSize is the adaptation rate of the filter (between 0 and 2).
+	StepSize float64
+
+	sampleRate uint32
+	taps       int
+	weights    []float64
+	// history is a double-length circular buffer of the most recent
+	// reference samples, so that a contiguous window of `taps` samples
+	// (newest first) is always available at history[pos:pos+taps]
+	history []float64
+	pos     int
+	energy  float64
+
+	mu      sync.Mutex
+	pending []float64
+}
+
+// NewEchoCanceller creates an echo canceller for audio with the given sample
+// rate. `tailLen` is the length of the echo path to model in seconds (use
+// `DefaultEchoTailLen` if unsure).
+func NewEchoCanceller(sampleRate uint32, tailLen float64) *EchoCanceller {
+	if sampleRate == 0 {
+		sampleRate = DefaultSampleRate
+	}
+	if tailLen <= 0 {
+		tailLen = DefaultEchoTailLen
+	}
+
+	taps := int(tailLen * float64(sampleRate))
+	if taps < 1 {
+		taps = 1
+	}
+
+	return &EchoCanceller{
+		StepSize:   DefaultEchoStepSize,
+		sampleRate: sampleRate,
+		taps:       taps,
+		weights:    make([]float64, taps),
+		history:    make([]float64, 2*taps),
+		pending:    make([]float64, 0),
+	}
+}
+
+// AddReference queues samples of the signal being played through the speaker
+// (at the canceller's sample rate). They are consumed by `Process` in step
+// with the microphone samples.
+func (e *EchoCanceller) AddReference(samples []float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.pending = append(e.pending, samples...)
+	if max := int(echoMaxPending * float64(e.sampleRate)); len(e.pending) > max {
+		e.pending = e.pending[len(e.pending)-max:]
+	}
+}
+
+// AttachPlayback starts forwarding all audio played with `File.Play` to this
+// echo canceller as its reference signal. Call `DetachPlayback` once the
+// recording has finished.
+func (e *EchoCanceller) AttachPlayback() {
+	playback.attach(e)
+}
+
+// DetachPlayback stops forwarding played audio to this echo canceller.
+func (e *EchoCanceller) DetachPlayback() {
+	playback.detach(e)
+}
+
+// Process removes the echo of the queued reference signal from a frame of
+// microphone samples. If not enough reference samples are queued, silence is
+// assumed to have been played.
+func (e *EchoCanceller) Process(frame []float64) []float64 {
+	e.mu.Lock()
+	ref := make([]float64, len(frame))
+	n := copy(ref, e.pending)
+	e.pending = e.pending[n:]
+	e.mu.Unlock()
+
+	return e.cancel(frame, ref)
+}
+
+// Sync discards any queued reference samples, so that the reference is
+// aligned with the microphone signal from now on. It is called when capture
+// starts, since audio played before then can't be aligned with the echo.
+// The learned echo path is kept.
+func (e *EchoCanceller) Sync() {
+	e.mu.Lock()
+	e.pending = e.pending[:0]
+	e.mu.Unlock()
+}
+
+// Reset clears the learned echo path and any queued reference samples.
+func (e *EchoCanceller) Reset() {
+	e.mu.Lock()
+	e.pending = e.pending[:0]
+	e.mu.Unlock()
+
+	for i := range e.weights {
+		e.weights[i] = 0
+	}
+	for i := range e.history {
+		e.history[i] = 0
+	}
+	e.pos = 0
+	e.energy = 0
+}
+
+// cancel runs the NLMS filter over the microphone signal `mic` using `ref`
+// as the time-aligned reference signal. Both slices must have the same
+// length. The result is written back into `mic`.
+func (e *EchoCanceller) cancel(mic []float64, ref []float64) []float64 {
+	// the peak of the reference over the block, for double-talk detection
+	peak := 0.0
+	for _, v := range e.history[e.pos : e.pos+e.taps] {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	for _, v := range ref {
+		peak = math.Max(peak, math.Abs(v))
+	}
+
+	for i := range mic {
+		// shift the new reference sample into the history, updating the
+		// running energy of the window
+		e.pos = (e.pos + e.taps - 1) % e.taps
+		old := e.history[e.pos]
+		e.history[e.pos] = ref[i]
+		e.history[e.pos+e.taps] = ref[i]
+		e.energy += ref[i]*ref[i] - old*old
+		if e.energy < 0 {
+			e.energy = 0
+		}
+
+		// estimate the echo and subtract it
+		x := e.history[e.pos : e.pos+e.taps]
+		estimate := 0.0
+		for k, w := range e.weights {
+			estimate += w * x[k]
+		}
+		residual := mic[i] - estimate
+
+		// adapt, unless the near-end user is talking over the playback
+		if math.Abs(mic[i]) < echoDoubleTalkThresh*peak {
+			g := e.StepSize * residual / (e.energy + 1e-6)
+			for k := range e.weights {
+				e.weights[k] += g * x[k]
+			}
+		}
+
+		mic[i] = residual
+	}
+	return mic
+}
+
+// CancelEcho removes the echo of `ref` (the audio that was being played while
+// this file was recorded) from the audio data in place. Both files should
+// start at the same instant; `ref` is converted to mono and resampled to
+// match this file if necessary.
+func (f *File) CancelEcho(ref *File) {
+	if ref == nil {
+		return
+	}
+
+	rate := f.AudioData.SampleRate
+	x := resample(mixDown(ref.AudioData.Samples()), ref.AudioData.SampleRate, rate)
+
+	channels := f.AudioData.Samples()
+	for c := range channels {
+		aligned := make([]float64, len(channels[c]))
+		copy(aligned, x)
+		channels[c] = NewEchoCanceller(rate, DefaultEchoTailLen).cancel(channels[c], aligned)
+	}
+	f.AudioData.SetSamples(channels)
+}
+
+// playbackMonitor forwards audio that is being played to any attached echo
+// cancellers.
+type playbackMonitor struct {
+	mu         sync.Mutex
+	cancellers map[*EchoCanceller]struct{}
+}
+
+// playback is the monitor that `File.Play` writes to.
+var playback = &playbackMonitor{cancellers: make(map[*EchoCanceller]struct{})}
+
+func (m *playbackMonitor) attach(e *EchoCanceller) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancellers[e] = struct{}{}
+}
+
+func (m *playbackMonitor) detach(e *EchoCanceller) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cancellers, e)
+}
+
+// write forwards a buffer of interleaved 16-bit samples that is about to be
+// played to the attached echo cancellers.
+func (m *playbackMonitor) write(buf []int16, numChannels uint16, sampleRate uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.cancellers) == 0 || numChannels == 0 {
+		return
+	}
+
+	// de-interleave and mix down to mono
+	frames := len(buf) / int(numChannels)
+	mono := make([]float64, frames)
+	for i := 0; i < frames; i++ {
+		for c := 0; c < int(numChannels); c++ {
+			mono[i] += float64(buf[i*int(numChannels)+c]) / (1 << 15)
+		}
+		mono[i] /= float64(numChannels)
+	}
+
+	for e := range m.cancellers {
+		e.AddReference(resample(mono, sampleRate, e.sampleRate))
+	}
+}
@@ -0,0 +1,102 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// echoOf simulates the acoustic path between a speaker and a microphone by
+// convolving the reference with a short, delayed impulse response.
+func echoOf(ref []float64) []float64 {
+	ir := map[int]float64{40: 0.3, 57: -0.12, 90: 0.05}
+	out := make([]float64, len(ref))
+	for i := range out {
+		for d, g := range ir {
+			if i >= d {
+				out[i] += g * ref[i-d]
+			}
+		}
+	}
+	return out
+}
+
+// After converging, the echo canceller should remove most of the echo of the
+// reference signal from the microphone signal.
+func TestCancelEcho(t *testing.T) {
+	n := 3 * int(audio.DefaultSampleRate)
+	ref := testutils.Noise(0.5, n, 1)
+	mic := echoOf(ref)
+
+	f := testutils.NewFile(audio.DefaultSampleRate, mic)
+	f.CancelEcho(testutils.NewFile(audio.DefaultSampleRate, ref))
+
+	// compare the last second (after convergence)
+	tail := int(audio.DefaultSampleRate)
+	before := testutils.Energy(mic[n-tail:])
+	after := testutils.Energy(f.AudioData.Samples()[0][n-tail:])
+	erle := 10 * math.Log10(before/after)
+	require.True(t, erle > 20, "echo return loss enhancement too low: %.1f dB", erle)
+}
+
+// Speech from the near-end user should be preserved while the echo is
+// cancelled, even if the user talks over the playback the whole time.
+func TestCancelEchoPreservesNearEnd(t *testing.T) {
+	n := 3 * int(audio.DefaultSampleRate)
+	ref := testutils.Noise(0.5, n, 2)
+	speech := testutils.Sine(440, 0.05, audio.DefaultSampleRate, n)
+	mic := echoOf(ref)
+	for i := range mic {
+		mic[i] += speech[i]
+	}
+
+	f := testutils.NewFile(audio.DefaultSampleRate, mic)
+	f.CancelEcho(testutils.NewFile(audio.DefaultSampleRate, ref))
+
+	tail := int(audio.DefaultSampleRate)
+	out := f.AudioData.Samples()[0][n-tail:]
+	residual := make([]float64, tail)
+	for i := range residual {
+		residual[i] = out[i] - speech[n-tail+i]
+	}
+	// the echo started out several times louder than the speech; afterwards
+	// whatever is left over should be well below it
+	require.True(t, testutils.Energy(residual) < 0.5*testutils.Energy(speech[n-tail:]))
+}
+
+// When used as a Processor, the canceller should consume queued reference
+// samples in step with the microphone frames.
+func TestEchoCancellerProcess(t *testing.T) {
+	n := 48 * audio.BufSize
+	ref := testutils.Noise(0.5, n, 3)
+	mic := echoOf(ref)
+
+	ec := audio.NewEchoCanceller(audio.DefaultSampleRate, audio.DefaultEchoTailLen)
+	out := make([]float64, 0, n)
+	for i := 0; i < n; i += audio.BufSize {
+		ec.AddReference(ref[i : i+audio.BufSize])
+		frame := append([]float64(nil), mic[i:i+audio.BufSize]...)
+		out = append(out, ec.Process(frame)...)
+	}
+
+	tail := int(audio.DefaultSampleRate)
+	require.Len(t, out, n)
+	require.True(t, testutils.Energy(out[n-tail:]) < 0.01*testutils.Energy(mic[n-tail:]))
+}
+
+// Reference audio queued before capture starts can't be aligned with the
+// microphone, so it is dropped rather than adapted to.
+func TestEchoCancellerSync(t *testing.T) {
+	ec := audio.NewEchoCanceller(audio.DefaultSampleRate, audio.DefaultEchoTailLen)
+	ec.AddReference(testutils.Noise(0.5, int(audio.DefaultSampleRate), 3))
+	ec.Sync()
+
+	mic := testutils.Noise(0.1, 8*audio.BufSize, 4)
+	for i := 0; i < len(mic); i += audio.BufSize {
+		frame := append([]float64(nil), mic[i:i+audio.BufSize]...)
+		require.Equal(t, mic[i:i+audio.BufSize], ec.Process(frame))
+	}
+}
@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"math"
+)
+
+const (
+	// DefaultAGCTargetLevel is the default RMS level (in dBFS) that automatic
+	// gain control aims for.
+	DefaultAGCTargetLevel = -20.0
+	// DefaultAGCMaxGain is the default maximum amplification (in dB) that
+	// automatic gain control will apply.
+	DefaultAGCMaxGain = 30.0
+
+	// agcLevelTime is the time constant (in seconds) of the level detector.
+	agcLevelTime = 0.05
+	// agcAttackTime and agcReleaseTime are the time constants (in seconds)
+	// with which the gain is decreased and increased respectively.
+	agcAttackTime  = 0.01
+	agcReleaseTime = 1.0
+	// agcGateLevel is the level (in dBFS) below which the input is treated
+	// as silence and the gain is held, so that background noise isn't
+	// amplified between words.
+	agcGateLevel = -50.0
+	// agcCeiling is the highest output amplitude allowed, to avoid clipping.
+	agcCeiling = 0.99
+)
+
+// AutomaticGainControl adjusts the level of audio in real time so that quiet
+// and loud microphones produce similar levels. It measures the RMS level of
+// the input and smoothly moves the gain towards the value needed to reach
+// the target level, quickly when the input gets louder and slowly when it
+// gets quieter. The gain is held during silence and reduced instantly if the
+// output would otherwise clip.
+//
+// It is a Processor, so it can be added to the recording pipeline or applied
+// to a file with `File.Apply`.
+type AutomaticGainControl struct {
+	// TargetLevel is the RMS level (in dBFS) to aim for.
+	TargetLevel float64
+	// MaxGain is the maximum amplification (in dB).
+	MaxGain float64
+
+	levelCoef   float64
+	attackCoef  float64
+	releaseCoef float64
+
+	power float64
+	gain  float64
+}
+
+// NewAutomaticGainControl creates an automatic gain control stage for audio
+// with the given sample rate, aiming for the given RMS level (in dBFS).
+func NewAutomaticGainControl(sampleRate uint32, targetLevel float64) *AutomaticGainControl {
+	if sampleRate == 0 {
+		sampleRate = DefaultSampleRate
+	}
+	rate := float64(sampleRate)
+	a := &AutomaticGainControl{
+		TargetLevel: targetLevel,
+		MaxGain:     DefaultAGCMaxGain,
+		levelCoef:   1 - math.Exp(-1/(agcLevelTime*rate)),
+		attackCoef:  1 - math.Exp(-1/(agcAttackTime*rate)),
+		releaseCoef: 1 - math.Exp(-1/(agcReleaseTime*rate)),
+	}
+	a.Reset()
+	return a
+}
+
+// Gain returns the gain (in dB) currently being applied.
+func (a *AutomaticGainControl) Gain() float64 {
+	return linearToDB(a.gain)
+}
+
+// Process adjusts the level of a frame of samples.
+func (a *AutomaticGainControl) Process(frame []float64) []float64 {
+	target := dbToLinear(a.TargetLevel)
+	maxGain := dbToLinear(a.MaxGain)
+	gate := dbToLinear(agcGateLevel)
+
+	for i, x := range frame {
+		a.power += a.levelCoef * (x*x - a.power)
+		level := math.Sqrt(a.power)
+
+		if level > gate {
+			desired := math.Min(target/level, maxGain)
+			if desired < a.gain {
+				a.gain += a.attackCoef * (desired - a.gain)
+			} else {
+				a.gain += a.releaseCoef * (desired - a.gain)
+			}
+		}
+
+		// never let the output clip
+		if y := math.Abs(x * a.gain); y > agcCeiling {
+			a.gain = agcCeiling / math.Abs(x)
+		}
+		frame[i] = x * a.gain
+	}
+	return frame
+}
+
+// Reset sets the gain back to unity and clears the level detector.
+func (a *AutomaticGainControl) Reset() {
+	a.power = 0
+	a.gain = 1
+}
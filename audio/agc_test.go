@@ -0,0 +1,44 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// A quiet signal should be brought up to the target level, and a loud one
+// brought down, without clipping.
+func TestAutomaticGainControl(t *testing.T) {
+	for _, amp := range []float64{0.005, 0.9} {
+		agc := audio.NewAutomaticGainControl(16000, audio.DefaultAGCTargetLevel)
+		in := testutils.Sine(300, amp, 16000, 10*16000)
+		out := make([]float64, 0, len(in))
+		for i := 0; i < len(in); i += audio.BufSize {
+			end := i + audio.BufSize
+			if end > len(in) {
+				end = len(in)
+			}
+			out = append(out, agc.Process(append([]float64(nil), in[i:end]...))...)
+		}
+
+		level := 20 * math.Log10(rmsOf(out[len(out)-16000:]))
+		require.InDelta(t, audio.DefaultAGCTargetLevel, level, 1, "input amplitude %v", amp)
+		require.True(t, peakOf(out) < 1)
+	}
+}
+
+// The gain should never exceed the maximum, and silence shouldn't be
+// amplified at all.
+func TestAutomaticGainControlLimits(t *testing.T) {
+	agc := audio.NewAutomaticGainControl(16000, audio.DefaultAGCTargetLevel)
+	agc.Process(testutils.Sine(300, 0.0001, 16000, 16000))
+	require.InDelta(t, 0, agc.Gain(), 1e-9)
+
+	agc.TargetLevel = -10
+	agc.Process(testutils.Sine(300, 0.006, 16000, 10*16000))
+	require.True(t, agc.Gain() <= audio.DefaultAGCMaxGain+1e-9)
+	require.True(t, agc.Gain() > audio.DefaultAGCMaxGain-1)
+}
@@ -0,0 +1,459 @@
+package audio
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+const (
+	// DefaultSpectrogramWindow and DefaultSpectrogramHop are the default
+	// length and step (in seconds) of the frames that make up a spectrogram.
+	DefaultSpectrogramWindow = 0.025
+	DefaultSpectrogramHop    = 0.01
+	// DefaultMeterWindow is the default length (in seconds) of each reading
+	// returned by `Levels`.
+	DefaultMeterWindow = 0.1
+
+	// MinPitch and MaxPitch are the range of fundamental frequencies (in Hz)
+	// that `Pitch` looks for, covering most human voices.
+	MinPitch = 50.0
+	MaxPitch = 500.0
+
+	// ClippingLevel is the level (relative to full scale) at or above which a
+	// sample is considered clipped.
+	ClippingLevel = 0.999
+
+	// spectrumFrameLen is the length (in samples) of the frames whose spectra
+	// are averaged by `Spectrum`. It is a power of two.
+	spectrumFrameLen = 4096
+	// spectrogramRange is the dynamic range (in dB) shown by spectrogram
+	// images. Anything quieter than this below the loudest point is black.
+	spectrogramRange = 80.0
+	// pitchFrameLen is the length (in seconds) of the frames whose pitch is
+	// estimated. It must cover at least two periods of `MinPitch`.
+	pitchFrameLen = 0.04
+	// pitchThreshold is the YIN threshold on the cumulative mean normalised
+	// difference below which a frame is considered voiced.
+	pitchThreshold = 0.15
+	// snrFrameLen is the length (in seconds) of the frames whose power is
+	// compared to estimate the signal to noise ratio.
+	snrFrameLen = 0.02
+	// snrNoisePercentile and snrSignalPercentile are the percentiles of the
+	// frame powers taken as the noise and signal levels respectively.
+	snrNoisePercentile  = 0.1
+	snrSignalPercentile = 0.9
+)
+
+// Spectrum is the magnitude spectrum of a piece of audio.
+type Spectrum struct {
+	// Frequencies is the centre frequency (in Hz) of each bin.
+	Frequencies []float64
+	// Magnitudes is the amplitude of each bin, scaled so that a sine wave
+	// with amplitude A shows a peak of (approximately) A.
+	Magnitudes []float64
+}
+
+// Peak returns the frequency (in Hz) of the loudest bin in the spectrum.
+func (s *Spectrum) Peak() float64 {
+	best := 0
+	for i, m := range s.Magnitudes {
+		if m > s.Magnitudes[best] {
+			best = i
+		}
+	}
+	if len(s.Frequencies) == 0 {
+		return 0
+	}
+	return s.Frequencies[best]
+}
+
+// Spectrogram is the short-time power spectrum of a piece of audio.
+type Spectrogram struct {
+	// Times is the centre (in seconds) of each frame.
+	Times []float64
+	// Frequencies is the centre frequency (in Hz) of each bin.
+	Frequencies []float64
+	// Power is the power (in dB relative to a full scale sine wave) of each
+	// bin, indexed by frame first and then by bin.
+	Power [][]float64
+}
+
+// Image renders the spectrogram as an image, with time running from left to
+// right and frequency from bottom to top. Louder bins are brighter, and the
+// colours cover 80dB below the loudest bin.
+func (s *Spectrogram) Image() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, len(s.Times), len(s.Frequencies)))
+	max := math.Inf(-1)
+	for _, frame := range s.Power {
+		for _, p := range frame {
+			max = math.Max(max, p)
+		}
+	}
+
+	for x, frame := range s.Power {
+		for bin, p := range frame {
+			v := 1 - (max-p)/spectrogramRange
+			img.Set(x, len(s.Frequencies)-1-bin, heatColor(v))
+		}
+	}
+	return img
+}
+
+// WritePNG renders the spectrogram (see `Image`) and writes it to `w` as a
+// PNG image.
+func (s *Spectrogram) WritePNG(w io.Writer) error {
+	return png.Encode(w, s.Image())
+}
+
+// Level is a reading from a level meter.
+type Level struct {
+	// Time is the start (in seconds) of the window that was measured.
+	Time float64
+	// Peak is the highest absolute sample value in the window, in dBFS.
+	Peak float64
+	// RMS is the root-mean-square level of the window, in dBFS.
+	RMS float64
+}
+
+// Spectrum computes the magnitude spectrum of the whole audio (mixed down to
+// mono) using Welch's method: the spectra of overlapping Hann windowed frames
+// of `spectrumFrameLen` samples are averaged, so the memory used doesn't grow
+// with the length of the audio. Audio shorter than a frame is analysed as a
+// single frame. Empty audio has an empty spectrum.
+func (f *File) Spectrum() *Spectrum {
+	rate := float64(f.AudioData.SampleRate)
+	mono := mixDown(f.AudioData.Samples())
+	if len(mono) == 0 || rate == 0 {
+		return &Spectrum{Frequencies: make([]float64, 0), Magnitudes: make([]float64, 0)}
+	}
+
+	frameLen := spectrumFrameLen
+	if len(mono) < frameLen {
+		frameLen = len(mono)
+	}
+	fftLen := nextPow2(frameLen)
+
+	// average the power of each bin, so that a steady tone keeps its
+	// amplitude
+	power := make([]float64, fftLen/2+1)
+	frames := 0
+	for start := 0; start+frameLen <= len(mono); start += frameLen / 2 {
+		for k, m := range magnitudes(mono[start:start+frameLen], fftLen) {
+			power[k] += m * m
+		}
+		frames++
+		if frameLen < 2 {
+			break
+		}
+	}
+
+	mags := make([]float64, len(power))
+	freqs := make([]float64, len(power))
+	for k := range power {
+		mags[k] = math.Sqrt(power[k] / float64(frames))
+		freqs[k] = float64(k) * rate / float64(fftLen)
+	}
+	return &Spectrum{Frequencies: freqs, Magnitudes: mags}
+}
+
+// Spectrogram computes the short-time power spectrum of the audio (mixed down
+// to mono), splitting it into Hann windowed frames of `window` seconds every
+// `hop` seconds. Use `DefaultSpectrogramWindow` and `DefaultSpectrogramHop`
+// for values suited to speech. Non-positive values are replaced by the
+// defaults.
+func (f *File) Spectrogram(window float64, hop float64) *Spectrogram {
+	if window <= 0 {
+		window = DefaultSpectrogramWindow
+	}
+	if hop <= 0 {
+		hop = DefaultSpectrogramHop
+	}
+
+	rate := float64(f.AudioData.SampleRate)
+	mono := mixDown(f.AudioData.Samples())
+	frameLen := int(math.Max(2, math.Round(window*rate)))
+	hopLen := int(math.Max(1, math.Round(hop*rate)))
+	fftLen := nextPow2(frameLen)
+
+	freqs := make([]float64, fftLen/2+1)
+	for k := range freqs {
+		freqs[k] = float64(k) * rate / float64(fftLen)
+	}
+
+	s := &Spectrogram{Times: make([]float64, 0), Frequencies: freqs, Power: make([][]float64, 0)}
+	for start := 0; start+frameLen <= len(mono); start += hopLen {
+		mags := magnitudes(mono[start:start+frameLen], fftLen)
+		power := make([]float64, len(mags))
+		for k, m := range mags {
+			power[k] = linearToDB(math.Max(m, 1e-10))
+		}
+		s.Times = append(s.Times, (float64(start)+float64(frameLen)/2)/rate)
+		s.Power = append(s.Power, power)
+	}
+	return s
+}
+
+// Pitch estimates the fundamental frequency (in Hz) of the audio (mixed down
+// to mono) using the YIN algorithm. The pitch of each voiced frame between
+// `MinPitch` and `MaxPitch` is estimated, and the median is returned. It
+// returns 0 if no voiced frames are found, or if the sample rate is too low
+// to hold a frame.
+func (f *File) Pitch() float64 {
+	rate := float64(f.AudioData.SampleRate)
+	mono := mixDown(f.AudioData.Samples())
+	frameLen := int(pitchFrameLen * rate)
+	if frameLen < 2 {
+		return 0
+	}
+
+	pitches := make([]float64, 0)
+	for start := 0; start+frameLen <= len(mono); start += frameLen / 2 {
+		frame := mono[start : start+frameLen]
+		if isSilentFrame(frame, DefaultSilenceThreshold) {
+			continue
+		}
+		if p := yin(frame, rate); p > 0 {
+			pitches = append(pitches, p)
+		}
+	}
+	if len(pitches) == 0 {
+		return 0
+	}
+	return percentile(pitches, 0.5)
+}
+
+// ClippedSamples returns the number of samples (across all channels) that are
+// at or above `ClippingLevel`, which usually means that the recording level
+// was too high.
+func (f *File) ClippedSamples() int {
+	n := 0
+	for _, ch := range f.AudioData.Samples() {
+		for _, v := range ch {
+			// the most negative value is one step further from zero than the
+			// most positive, so compare against the positive level
+			if math.Abs(v) >= ClippingLevel {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// SNR estimates the signal to noise ratio (in dB) of the audio, by comparing
+// the power of the loudest frames (taken to be speech) to the noise in the
+// quietest frames. The noise in a frame is estimated from the floor of its
+// spectrum, so pauses give the best estimate, but audio without any pauses
+// (whose quietest frames still contain speech) isn't mistaken for noise. It
+// returns positive infinity if the quietest frames are completely silent,
+// and NaN if the audio is shorter than a frame.
+func (f *File) SNR() float64 {
+	rate := float64(f.AudioData.SampleRate)
+	mono := mixDown(f.AudioData.Samples())
+	frameLen := int(math.Max(1, snrFrameLen*rate))
+	fftLen := nextPow2(frameLen)
+
+	powers := make([]float64, 0, len(mono)/frameLen)
+	noises := make([]float64, 0, len(mono)/frameLen)
+	for start := 0; start+frameLen <= len(mono); start += frameLen {
+		frame := mono[start : start+frameLen]
+		r := rms(frame)
+		powers = append(powers, r*r)
+		noises = append(noises, r*r*noiseFraction(frame, fftLen))
+	}
+	if len(powers) == 0 {
+		return math.NaN()
+	}
+
+	noise := percentile(noises, snrNoisePercentile)
+	signal := percentile(powers, snrSignalPercentile)
+	if noise == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(math.Max(signal-noise, 1e-20)/noise)
+}
+
+// noiseFraction estimates the fraction (between 0 and 1) of the power of a
+// frame that is broadband noise, from the floor of its spectrum. The power
+// in each bin of white noise is exponentially distributed, so its median is
+// ln(2) times its mean, and a frame of noise gives (about) 1. The power of
+// speech is concentrated in a few bins (harmonics and formants), which
+// hardly affect the median.
+func noiseFraction(frame []float64, fftLen int) float64 {
+	mags := magnitudes(frame, fftLen)
+	if len(mags) < 3 {
+		return 1
+	}
+	// ignore DC and the Nyquist bin, which behave differently
+	bins := make([]float64, 0, len(mags))
+	total := 0.0
+	for _, m := range mags[1 : len(mags)-1] {
+		bins = append(bins, m*m)
+		total += m * m
+	}
+	if len(bins) == 0 || total == 0 {
+		return 1
+	}
+	floor := percentile(bins, 0.5) / math.Ln2 * float64(len(bins))
+	return math.Min(1, floor/total)
+}
+
+// Peak returns the highest absolute sample value (across all channels) in
+// dBFS. It returns negative infinity for silent audio.
+func (f *File) Peak() float64 {
+	peak := 0.0
+	for _, ch := range f.AudioData.Samples() {
+		for _, v := range ch {
+			peak = math.Max(peak, math.Abs(v))
+		}
+	}
+	return linearToDB(peak)
+}
+
+// RMS returns the root-mean-square level (across all channels) in dBFS. It
+// returns negative infinity for silent audio.
+func (f *File) RMS() float64 {
+	all := make([]float64, 0, f.numFrames()*int(f.AudioData.NumChannels))
+	for _, ch := range f.AudioData.Samples() {
+		all = append(all, ch...)
+	}
+	return linearToDB(rms(all))
+}
+
+// Levels meters the audio over time, returning the peak and RMS levels (in
+// dBFS, across all channels) of consecutive windows of `window` seconds (see
+// `DefaultMeterWindow`). The last window may be shorter.
+func (f *File) Levels(window float64) []Level {
+	rate := float64(f.AudioData.SampleRate)
+	channels := f.AudioData.Samples()
+	if len(channels) == 0 || rate == 0 {
+		return make([]Level, 0)
+	}
+	n := f.numFrames()
+	windowLen := int(math.Max(1, math.Round(window*rate)))
+
+	levels := make([]Level, 0, n/windowLen+1)
+	for start := 0; start < n; start += windowLen {
+		end := int(math.Min(float64(start+windowLen), float64(n)))
+		peak, sum := 0.0, 0.0
+		for _, ch := range channels {
+			for _, v := range ch[start:end] {
+				peak = math.Max(peak, math.Abs(v))
+				sum += v * v
+			}
+		}
+		levels = append(levels, Level{
+			Time: float64(start) / rate,
+			Peak: linearToDB(peak),
+			RMS:  linearToDB(math.Sqrt(sum / float64((end-start)*len(channels)))),
+		})
+	}
+	return levels
+}
+
+// magnitudes computes the amplitude spectrum of `x` with a Hann window,
+// zero padded to `fftLen` samples (a power of two). Only the non-negative
+// frequencies are returned.
+func magnitudes(x []float64, fftLen int) []float64 {
+	window := hann(len(x))
+	sum := 0.0
+	buf := make([]complex128, fftLen)
+	for i, v := range x {
+		buf[i] = complex(v*window[i], 0)
+		sum += window[i]
+	}
+	fft(buf)
+
+	mags := make([]float64, fftLen/2+1)
+	if sum == 0 {
+		return mags
+	}
+	for k := range mags {
+		mags[k] = 2 * cmplx.Abs(buf[k]) / sum
+	}
+	return mags
+}
+
+// yin estimates the fundamental frequency of a frame using the YIN algorithm
+// (de Cheveigné and Kawahara, 2002), returning 0 if the frame isn't periodic
+// within the range of `MinPitch` to `MaxPitch`.
+func yin(frame []float64, rate float64) float64 {
+	minLag := int(rate / MaxPitch)
+	maxLag := int(math.Min(rate/MinPitch, float64(len(frame)/2)))
+	if minLag < 1 || maxLag <= minLag {
+		return 0
+	}
+
+	// cumulative mean normalised difference function
+	width := len(frame) - maxLag
+	d := make([]float64, maxLag+1)
+	d[0] = 1
+	total := 0.0
+	for lag := 1; lag <= maxLag; lag++ {
+		diff := 0.0
+		for i := 0; i < width; i++ {
+			delta := frame[i] - frame[i+lag]
+			diff += delta * delta
+		}
+		total += diff
+		if total == 0 {
+			d[lag] = 1
+		} else {
+			d[lag] = diff * float64(lag) / total
+		}
+	}
+
+	// first dip below the threshold, followed down to its minimum
+	for lag := minLag; lag < maxLag; lag++ {
+		if d[lag] >= pitchThreshold {
+			continue
+		}
+		for lag+1 < maxLag && d[lag+1] < d[lag] {
+			lag++
+		}
+
+		// parabolic interpolation for sub-sample accuracy
+		a, b, c := d[lag-1], d[lag], d[lag+1]
+		shift := 0.0
+		if denom := a - 2*b + c; denom != 0 {
+			shift = 0.5 * (a - c) / denom
+		}
+		return rate / (float64(lag) + shift)
+	}
+	return 0
+}
+
+// percentile returns the value at the given fraction (between 0 and 1) of
+// the sorted values, without modifying them.
+func percentile(values []float64, p float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// heatColor maps a value between 0 and 1 to a colour that goes from black
+// through blue, red and yellow to white. Values outside of the range are
+// clamped.
+func heatColor(v float64) color.RGBA {
+	v = math.Max(0, math.Min(1, v))
+	stops := []color.RGBA{
+		{0, 0, 0, 255},
+		{0, 0, 160, 255},
+		{200, 0, 80, 255},
+		{255, 200, 0, 255},
+		{255, 255, 255, 255},
+	}
+	pos := v * float64(len(stops)-1)
+	i := int(math.Min(pos, float64(len(stops)-2)))
+	t := pos - float64(i)
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+	}
+	lo, hi := stops[i], stops[i+1]
+	return color.RGBA{lerp(lo.R, hi.R), lerp(lo.G, hi.G), lerp(lo.B, hi.B), 255}
+}
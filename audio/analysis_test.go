@@ -0,0 +1,170 @@
+package audio_test
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpectrum(t *testing.T) {
+	f := testutils.NewFile(16000, testutils.Sine(1000, 0.5, 16000, 16000))
+	s := f.Spectrum()
+	require.Equal(t, len(s.Frequencies), len(s.Magnitudes))
+	require.InDelta(t, 8000, s.Frequencies[len(s.Frequencies)-1], 1e-9)
+	require.InDelta(t, 1000, s.Peak(), 1)
+
+	peak := 0.0
+	for _, m := range s.Magnitudes {
+		peak = math.Max(peak, m)
+	}
+	require.InDelta(t, 0.5, peak, 0.05)
+}
+
+// Long audio is averaged over frames, and short audio is analysed as a single
+// frame.
+func TestSpectrumLengths(t *testing.T) {
+	long := testutils.NewFile(16000, testutils.Sine(1000, 0.5, 16000, 10*16000)).Spectrum()
+	require.Len(t, long.Magnitudes, 2049)
+	require.InDelta(t, 1000, long.Peak(), 4)
+
+	short := testutils.NewFile(16000, testutils.Sine(2000, 0.5, 16000, 512)).Spectrum()
+	require.Len(t, short.Magnitudes, 257)
+	require.InDelta(t, 2000, short.Peak(), 32)
+
+	one := testutils.NewFile(16000, []float64{0.5}).Spectrum()
+	require.Len(t, one.Frequencies, 1)
+	require.Equal(t, 0.0, one.Frequencies[0])
+}
+
+// Empty audio, and audio without any channels, have no spectrum or levels.
+func TestAnalysisEmpty(t *testing.T) {
+	empty := testutils.NewFile(16000, []float64{})
+	s := empty.Spectrum()
+	require.Empty(t, s.Frequencies)
+	require.Empty(t, s.Magnitudes)
+	require.Equal(t, 0.0, s.Peak())
+	require.Empty(t, empty.Levels(audio.DefaultMeterWindow))
+
+	wav := audio.NewWAV()
+	wav.NumChannels = 0
+	wav.AddAudioData(make([]byte, 100))
+	noChannels := &audio.File{AudioData: wav}
+	require.Empty(t, noChannels.Spectrum().Magnitudes)
+	require.Empty(t, noChannels.Levels(audio.DefaultMeterWindow))
+}
+
+// A tone that changes frequency half way through shows up in different bins
+// over time, and the image has one column per frame.
+func TestSpectrogram(t *testing.T) {
+	samples := append(testutils.Sine(500, 0.5, 16000, 8000), testutils.Sine(3000, 0.5, 16000, 8000)...)
+	s := testutils.NewFile(16000, samples).Spectrogram(audio.DefaultSpectrogramWindow, audio.DefaultSpectrogramHop)
+	require.Len(t, s.Times, len(s.Power))
+	require.InDelta(t, 98, len(s.Times), 1)
+
+	loudest := func(frame []float64) float64 {
+		best := 0
+		for k, p := range frame {
+			if p > frame[best] {
+				best = k
+			}
+		}
+		return s.Frequencies[best]
+	}
+	binWidth := s.Frequencies[1]
+	require.InDelta(t, 500, loudest(s.Power[10]), binWidth)
+	require.InDelta(t, 3000, loudest(s.Power[len(s.Power)-10]), binWidth)
+	require.InDelta(t, -6, s.Power[10][int(math.Round(500/binWidth))], 2)
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, s.WritePNG(buf))
+	img, err := png.Decode(buf)
+	require.Nil(t, err)
+	require.Equal(t, len(s.Times), img.Bounds().Dx())
+	require.Equal(t, len(s.Frequencies), img.Bounds().Dy())
+}
+
+func TestPitch(t *testing.T) {
+	for _, freq := range []float64{110, 220, 330} {
+		f := testutils.NewFile(16000, testutils.Sine(freq, 0.3, 16000, 8000))
+		require.InDelta(t, freq, f.Pitch(), 1, "%vHz", freq)
+	}
+
+	// a harmonic-rich tone still reports its fundamental
+	s := testutils.Sine(150, 0.3, 16000, 8000)
+	for i, v := range testutils.Sine(450, 0.2, 16000, 8000) {
+		s[i] += v
+	}
+	require.InDelta(t, 150, testutils.NewFile(16000, s).Pitch(), 1)
+
+	require.Equal(t, 0.0, testutils.NewFile(16000, make([]float64, 8000)).Pitch())
+	require.Equal(t, 0.0, testutils.NewFile(16000, testutils.Noise(0.3, 8000, 1)).Pitch())
+
+	// sample rates too low to analyse, e.g. from a bad header
+	for _, rate := range []uint32{0, 1, 40, 50, 100} {
+		f := testutils.NewFile(16000, testutils.Sine(10, 0.5, 100, 1000))
+		f.AudioData.SampleRate = rate
+		require.Equal(t, 0.0, f.Pitch(), "%vHz", rate)
+	}
+}
+
+func TestClippedSamples(t *testing.T) {
+	require.Equal(t, 0, testutils.NewFile(16000, testutils.Sine(440, 0.9, 16000, 16000)).ClippedSamples())
+
+	f := testutils.NewFile(16000, testutils.Sine(440, 0.9, 16000, 16000))
+	f.ApplyGain(12)
+	require.True(t, f.ClippedSamples() > 16000/4)
+}
+
+func TestSNR(t *testing.T) {
+	// a second of tone at -10dBFS (RMS) then a second of noise at -50dBFS
+	samples := testutils.Sine(440, 0.1*math.Sqrt2, 16000, 16000)
+	noise := testutils.Noise(0.01*math.Sqrt(3), 32000, 1)
+	samples = append(samples, make([]float64, 16000)...)
+	for i := range samples {
+		samples[i] += noise[i]
+	}
+	require.InDelta(t, 20, testutils.NewFile(16000, samples).SNR(), 1)
+
+	require.True(t, math.IsInf(testutils.NewFile(16000, append(make([]float64, 16000), samples[:16000]...)).SNR(), 1))
+
+	// too short to estimate
+	require.True(t, math.IsNaN(testutils.NewFile(16000, samples[:100]).SNR()))
+}
+
+// Speech without any pauses isn't mistaken for noise.
+func TestSNRWithoutPauses(t *testing.T) {
+	// a gliding tone whose level rises and falls like syllables
+	samples := make([]float64, 16000)
+	noise := testutils.Noise(0.001, len(samples), 5)
+	phase := 0.0
+	for i := range samples {
+		t := float64(i) / 16000
+		phase += 2 * math.Pi * (150 + 100*t) / 16000
+		samples[i] = (0.3+0.2*math.Sin(2*math.Pi*4*t))*math.Sin(phase) + noise[i]
+	}
+	f := testutils.NewFile(16000, samples)
+	require.True(t, f.SNR() > 30, "SNR was %.1fdB", f.SNR())
+	require.Nil(t, f.Quality().Check(nil))
+}
+
+func TestLevels(t *testing.T) {
+	samples := append(testutils.Sine(440, 0.5, 16000, 16000), testutils.Sine(440, 0.05, 16000, 8000)...)
+	f := testutils.NewFile(16000, samples)
+
+	require.InDelta(t, -6.02, f.Peak(), 0.01)
+	levels := f.Levels(audio.DefaultMeterWindow)
+	require.Len(t, levels, 15)
+	require.Equal(t, 0.0, levels[0].Time)
+	require.InDelta(t, 1.4, levels[14].Time, 1e-9)
+	require.InDelta(t, -6.02, levels[0].Peak, 0.01)
+	require.InDelta(t, -9.03, levels[0].RMS, 0.05)
+	require.InDelta(t, -26.02, levels[14].Peak, 0.01)
+	require.InDelta(t, -29.03, levels[14].RMS, 0.05)
+
+	require.True(t, math.IsInf(testutils.NewFile(16000, make([]float64, 100)).RMS(), -1))
+}
@@ -2,10 +2,11 @@
 package audio
 
 import (
-	"bufio"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 
 	"github.com/auroraapi/aurora-go/errors"
@@ -104,6 +105,15 @@ func (f *File) Stop() {
 
 // Play the audio file to the default output
 func (f *File) Play() error {
+	return f.PlayWithProgress(nil)
+}
+
+// PlayWithProgress plays the audio file like `Play`, calling `onProgress`
+// with the position (in seconds) that playback has reached each time a
+// buffer of audio has been handed to the output. It can be used to keep
+// something, such as highlighted text, in step with the audio. It is called
+// from the playback loop, so it must return quickly.
+func (f *File) PlayWithProgress(onProgress func(seconds float64)) error {
 	// initialize the underlying APIs for audio transmission
 	portaudio.Initialize()
 	defer portaudio.Terminate()
@@ -140,45 +150,177 @@ func (f *File) Play() error {
 			k := j * 2
 			buf[j] = int16(binary.LittleEndian.Uint16(data[i+k : i+k+2]))
 		}
+		// let any echo cancellers know what is about to be played
+		playback.write(buf, f.AudioData.NumChannels, f.AudioData.SampleRate)
 		// write the converted data into the stream
 		err := stream.Write()
 		if err != nil {
 			return errors.NewFromErrorCodeInfo(errors.AudioFileNotWritableStream, err.Error())
 		}
+		if onProgress != nil {
+			played := float64((i+step)/2/int(f.AudioData.NumChannels)) / float64(f.AudioData.SampleRate)
+			onProgress(math.Min(played, f.Duration()))
+		}
 	}
 
 	return nil
 }
 
+// PlayStream plays a WAV stream (such as an `api.TTSStream`) to the default
+// output as it is read, rather than waiting for all of it like `File.Play`.
+// It returns once the stream ends; to stop playback early, close the stream.
+// The audio must be 16-bit.
+func PlayStream(r io.Reader) error {
+	header := make([]byte, wavHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return errors.NewFromErrorCodeInfo(errors.WAVCorruptFile, err.Error())
+	}
+	wav, err := NewWAVFromData(header)
+	if err != nil {
+		return err
+	}
+	if wav.BitsPerSample != 16 || wav.NumChannels == 0 {
+		return errors.NewFromErrorCodeInfo(errors.AudioFileInvalidFormat, fmt.Sprintf("Streams can only play 16-bit audio, but the stream has %d channel(s), %d bits per sample.", wav.NumChannels, wav.BitsPerSample))
+	}
+
+	portaudio.Initialize()
+	defer portaudio.Terminate()
+
+	buf := make([]int16, BufSize*int(wav.NumChannels))
+	stream, err := portaudio.OpenDefaultStream(0, int(wav.NumChannels), float64(wav.SampleRate), BufSize, buf)
+	if err != nil {
+		return errors.NewFromErrorCodeInfo(errors.AudioFileOutputStreamNotOpened, err.Error())
+	}
+	defer stream.Close()
+	defer stream.Stop()
+	stream.Start()
+
+	return readBuffers(r, buf, func() error {
+		playback.write(buf, wav.NumChannels, wav.SampleRate)
+		if err := stream.Write(); err != nil {
+			return errors.NewFromErrorCodeInfo(errors.AudioFileNotWritableStream, err.Error())
+		}
+		return nil
+	})
+}
+
+// readBuffers fills `buf` with 16-bit samples read from `r`, calling `play`
+// each time it is full. The last buffer is padded with silence.
+func readBuffers(r io.Reader, buf []int16, play func() error) error {
+	data := make([]byte, len(buf)*2)
+	for {
+		n, err := io.ReadFull(r, data)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		for i := range buf {
+			buf[i] = 0
+			if 2*i+1 < n {
+				buf[i] = int16(binary.LittleEndian.Uint16(data[2*i:]))
+			}
+		}
+		if err := play(); err != nil {
+			return err
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil
+		}
+	}
+}
+
+// RecordingParams configures a recording session started with
+// `NewFileFromRecordingParams` or `NewRecordingStreamFromParams`.
+type RecordingParams struct {
+	// Length is the maximum length of the recording in seconds. A value of
+	// 0 means that recording continues until `SilenceLen` seconds of silence.
+	Length float64
+	// SilenceLen is how much consecutive silence (in seconds) to wait for
+	// before ending the recording. It is only used if `Length` is 0.
+	SilenceLen float64
+	// Processors are run, in order, over the audio as it is captured (before
+	// silence detection). They can be used for echo cancellation, noise
+	// suppression, etc.
+	Processors []Processor
+	// OnEvent, if set, is called with the level of each buffer of audio as
+	// it is read and whenever the state of the recording changes (see
+	// `EventType`). It is called from the recording goroutine, so it must
+	// return quickly to avoid dropping audio.
+	OnEvent func(Event)
+	// BufferSize is how many buffers of audio (of `BufSize` samples each)
+	// are held while waiting to be consumed. A value of 0 means
+	// `DefaultRingBufferSize`.
+	BufferSize int
+	// Overflow decides what happens if the buffer fills up because the audio
+	// isn't consumed quickly enough. The default is `OverflowBlock`.
+	Overflow OverflowPolicy
+}
+
 // NewRecordingStream records audio data according to the given parameters (just
 // like `NewFileFromRecording`), however instead of creating an *audio.File, it
 // streams the data to a Reader, which can be read as soon as data is available.
 // It emits a WAV header before the actual data, but the length of the data is
 // not correct. Therefore, the resulting WAV should be read until EOF.
 func NewRecordingStream(length float64, silenceLen float64) io.Reader {
-	pr, pw := io.Pipe()
-	// Create a large buffer so that we don't block recording if the
-	// consumption of this data is too slow.
-	bufwr := bufio.NewWriterSize(pw, 1000*BufSize)
-	bufrd := bufio.NewReaderSize(pr, 1000*BufSize)
-
-	go func() {
-		defer pw.Close()
-		defer bufwr.Flush()
-
-		header := NewWAV().Data()
-		bufwr.Write(header)
-
-		ch := record(length, silenceLen)
-		for d := range ch {
-			if d.Error != nil {
-				return
-			}
-			bufwr.Write(d.Data)
+	return NewRecordingStreamFromParams(&RecordingParams{Length: length, SilenceLen: silenceLen})
+}
+
+// NewRecordingStreamFromParams is like `NewRecordingStream`, but takes the full
+// set of recording parameters.
+func NewRecordingStreamFromParams(params *RecordingParams) *RecordingStream {
+	return &RecordingStream{buffer: record(params)}
+}
+
+// RecordingStream is a recording in progress that can be read as a WAV
+// stream. Audio is held in a fixed-size ring buffer until it is read, and
+// the buffer's `OverflowPolicy` decides what happens if it isn't read
+// quickly enough.
+type RecordingStream struct {
+	buffer     *RingBuffer
+	pending    []byte
+	headerSent bool
+}
+
+// Read reads the next part of the WAV stream, blocking until audio is
+// available. It returns `io.EOF` once the recording has finished, or the
+// error that stopped the recording.
+func (s *RecordingStream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if !s.headerSent {
+			s.pending = NewWAV().Data()
+			s.headerSent = true
+			continue
+		}
+		samples, err := s.buffer.Read()
+		if err != nil {
+			return 0, err
 		}
-	}()
+		s.pending = int16ToBytes(samples)
+	}
 
-	return bufrd
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Close stops the recording. Audio that has already been recorded can still
+// be read.
+func (s *RecordingStream) Close() error {
+	s.buffer.Close(nil)
+	return nil
+}
+
+// Dropped returns the number of buffers of audio that were discarded because
+// the stream wasn't read quickly enough (only with `OverflowDropOldest`).
+func (s *RecordingStream) Dropped() uint64 {
+	return s.buffer.Dropped()
+}
+
+// Buffered returns the number of buffers of audio waiting to be read.
+func (s *RecordingStream) Buffered() int {
+	return s.buffer.Len()
 }
 
 // NewFileFromRecording creates a new audio.File by recording from the default
@@ -186,14 +328,24 @@ func NewRecordingStream(length float64, silenceLen float64) io.Reader {
 // seconds. `silenceLen` specifies in seconds how much consecutive silence to
 // wait for before ending the recording.
 func NewFileFromRecording(length float64, silenceLen float64) (*File, error) {
-	ch := record(length, silenceLen)
+	return NewFileFromRecordingParams(&RecordingParams{Length: length, SilenceLen: silenceLen})
+}
+
+// NewFileFromRecordingParams is like `NewFileFromRecording`, but takes the full
+// set of recording parameters.
+func NewFileFromRecordingParams(params *RecordingParams) (*File, error) {
+	buffer := record(params)
 	audioData := make([]byte, 0)
-	for d := range ch {
-		if d.Error != nil {
-			return nil, d.Error
+	for {
+		samples, err := buffer.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
 		}
 
-		audioData = append(audioData, d.Data...)
+		audioData = append(audioData, int16ToBytes(samples)...)
 	}
 
 	return &File{
@@ -206,6 +358,36 @@ func NewFileFromRecording(length float64, silenceLen float64) (*File, error) {
 	}, nil
 }
 
+// RecordToFile records audio like `NewFileFromRecordingParams`, but streams it
+// straight to disk as it is captured (see `FileSink`) instead of holding it in
+// memory, which suits long recordings. It returns the paths of the files that
+// were written, even if the recording stopped with an error.
+func RecordToFile(params *RecordingParams, sinkParams *FileSinkParams) ([]string, error) {
+	sink, err := NewFileSink(sinkParams, SampleRate, NumChannels)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := record(params)
+	// stop recording if we can't write to the file
+	defer buffer.Close(nil)
+	for {
+		samples, err := buffer.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = sink.Write(samples)
+		}
+		if err != nil {
+			sink.Close()
+			return sink.Files(), err
+		}
+	}
+
+	return sink.Files(), sink.Close()
+}
+
 // NewFileFromBytes creates a new audio.File from WAV data
 func NewFileFromBytes(b []byte) (*File, error) {
 	wav, err := NewWAVFromData(b)
@@ -0,0 +1,287 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+// resampleZeroCrossings is the number of zero crossings on either side of the
+// windowed sinc kernel used for sample rate conversion. More gives a sharper
+// anti-aliasing filter at the cost of speed.
+const resampleZeroCrossings = 16
+
+// Duration returns the length of the audio data in seconds.
+func (f *File) Duration() float64 {
+	return float64(f.numFrames()) / float64(f.AudioData.SampleRate)
+}
+
+// Clone returns a deep copy of the audio file.
+func (f *File) Clone() *File {
+	data := make([]byte, len(f.AudioData.AudioData()))
+	copy(data, f.AudioData.AudioData())
+	return &File{AudioData: NewWAVFromParams(&WAVParams{
+		NumChannels:   f.AudioData.NumChannels,
+		SampleRate:    f.AudioData.SampleRate,
+		BitsPerSample: f.AudioData.BitsPerSample,
+		AudioData:     data,
+	})}
+}
+
+// Slice returns a new audio file containing the audio between `start` and
+// `end` (in seconds). It returns an error if the range is not within the
+// audio data.
+func (f *File) Slice(start float64, end float64) (*File, error) {
+	rate := float64(f.AudioData.SampleRate)
+	return f.SliceSamples(int(math.Round(start*rate)), int(math.Round(end*rate)))
+}
+
+// SliceSamples returns a new audio file containing the samples in the range
+// [start, end). For multi-channel audio, a sample includes every channel. It
+// returns an error if the range is not within the audio data.
+func (f *File) SliceSamples(start int, end int) (*File, error) {
+	if start < 0 || end < start || end > f.numFrames() {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidRange, fmt.Sprintf("Requested samples [%d, %d) of %d.", start, end, f.numFrames()))
+	}
+
+	frameSize := f.frameSize()
+	data := make([]byte, (end-start)*frameSize)
+	copy(data, f.AudioData.AudioData()[start*frameSize:end*frameSize])
+	return &File{AudioData: NewWAVFromParams(&WAVParams{
+		NumChannels:   f.AudioData.NumChannels,
+		SampleRate:    f.AudioData.SampleRate,
+		BitsPerSample: f.AudioData.BitsPerSample,
+		AudioData:     data,
+	})}, nil
+}
+
+// ConvertTo returns a new audio file with the given sample rate, number of
+// channels and bit depth. Sample rate conversion uses band-limited (windowed
+// sinc) interpolation. Converting to mono averages the channels, and
+// converting from mono duplicates the channel.
+func (f *File) ConvertTo(sampleRate uint32, numChannels uint16, bitsPerSample uint16) (*File, error) {
+	if sampleRate == 0 || numChannels == 0 || (bitsPerSample != 8 && bitsPerSample != 16 && bitsPerSample != 24 && bitsPerSample != 32) {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidFormat, fmt.Sprintf("Requested %dHz, %d channel(s), %d bits per sample.", sampleRate, numChannels, bitsPerSample))
+	}
+
+	in := f.AudioData.Samples()
+	out := make([][]float64, numChannels)
+	for c := range out {
+		if numChannels == 1 || c >= len(in) {
+			out[c] = mixDown(in)
+		} else {
+			out[c] = in[c]
+		}
+		out[c] = resampleBandlimited(out[c], f.AudioData.SampleRate, sampleRate)
+	}
+
+	return newFileFromSamples(sampleRate, bitsPerSample, out), nil
+}
+
+// Concat returns a new audio file with the given files appended, in order, to
+// the end of this one. Files with a different format are converted to the
+// format of this file first.
+func (f *File) Concat(others ...*File) (*File, error) {
+	channels := f.AudioData.Samples()
+	for _, other := range others {
+		converted, err := other.convertToMatch(f)
+		if err != nil {
+			return nil, err
+		}
+		for c, ch := range converted.AudioData.Samples() {
+			channels[c] = append(channels[c], ch...)
+		}
+	}
+	return f.withSamples(channels), nil
+}
+
+// Mix returns a new audio file with `other` overlaid on this one, starting
+// `offset` seconds in and amplified by `gain` dB (use 0 to mix at the original
+// level). The result is extended if `other` runs past the end of this file.
+// `other` is converted to the format of this file first.
+func (f *File) Mix(other *File, offset float64, gain float64) (*File, error) {
+	if offset < 0 {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidRange, fmt.Sprintf("Cannot mix at negative offset %f.", offset))
+	}
+	converted, err := other.convertToMatch(f)
+	if err != nil {
+		return nil, err
+	}
+
+	start := int(math.Round(offset * float64(f.AudioData.SampleRate)))
+	g := dbToLinear(gain)
+	channels := f.AudioData.Samples()
+	for c, ch := range converted.AudioData.Samples() {
+		if need := start + len(ch); need > len(channels[c]) {
+			channels[c] = append(channels[c], make([]float64, need-len(channels[c]))...)
+		}
+		for i, v := range ch {
+			channels[c][start+i] += g * v
+		}
+	}
+	return f.withSamples(channels), nil
+}
+
+// FadeIn returns a new audio file whose first `seconds` fade in linearly from
+// silence.
+func (f *File) FadeIn(seconds float64) *File {
+	channels := f.AudioData.Samples()
+	n := f.secondsToFrames(seconds)
+	for _, ch := range channels {
+		for i := 0; i < n; i++ {
+			ch[i] *= float64(i) / float64(n)
+		}
+	}
+	return f.withSamples(channels)
+}
+
+// FadeOut returns a new audio file whose last `seconds` fade out linearly to
+// silence.
+func (f *File) FadeOut(seconds float64) *File {
+	channels := f.AudioData.Samples()
+	n := f.secondsToFrames(seconds)
+	for _, ch := range channels {
+		for i := 0; i < n; i++ {
+			ch[len(ch)-1-i] *= float64(i) / float64(n)
+		}
+	}
+	return f.withSamples(channels)
+}
+
+// Crossfade returns a new audio file with `other` appended to this one, where
+// the last `seconds` of this file overlap with the first `seconds` of
+// `other`, fading from one to the other with equal power. `other` is
+// converted to the format of this file first.
+func (f *File) Crossfade(other *File, seconds float64) (*File, error) {
+	converted, err := other.convertToMatch(f)
+	if err != nil {
+		return nil, err
+	}
+
+	n := f.secondsToFrames(seconds)
+	if n > converted.numFrames() {
+		n = converted.numFrames()
+	}
+
+	channels := f.AudioData.Samples()
+	for c, ch := range converted.AudioData.Samples() {
+		overlap := channels[c][len(channels[c])-n:]
+		for i := range overlap {
+			t := (float64(i) + 0.5) / float64(n) * math.Pi / 2
+			overlap[i] = overlap[i]*math.Cos(t) + ch[i]*math.Sin(t)
+		}
+		channels[c] = append(channels[c], ch[n:]...)
+	}
+	return f.withSamples(channels), nil
+}
+
+// Reverse returns a new audio file with the audio played backwards.
+func (f *File) Reverse() *File {
+	channels := f.AudioData.Samples()
+	for _, ch := range channels {
+		for i, j := 0, len(ch)-1; i < j; i, j = i+1, j-1 {
+			ch[i], ch[j] = ch[j], ch[i]
+		}
+	}
+	return f.withSamples(channels)
+}
+
+// numFrames returns the number of samples in each channel.
+func (f *File) numFrames() int {
+	if f.frameSize() == 0 {
+		return 0
+	}
+	return len(f.AudioData.AudioData()) / f.frameSize()
+}
+
+// frameSize returns the number of bytes taken up by one sample of every
+// channel.
+func (f *File) frameSize() int {
+	return int(f.AudioData.NumChannels) * int(f.AudioData.BitsPerSample/8)
+}
+
+// secondsToFrames converts a duration to a number of samples, limited to the
+// length of the audio.
+func (f *File) secondsToFrames(seconds float64) int {
+	n := int(math.Round(seconds * float64(f.AudioData.SampleRate)))
+	if n < 0 {
+		return 0
+	}
+	if n > f.numFrames() {
+		return f.numFrames()
+	}
+	return n
+}
+
+// withSamples creates a new audio file in the same format as this one with
+// the given samples.
+func (f *File) withSamples(channels [][]float64) *File {
+	return newFileFromSamples(f.AudioData.SampleRate, f.AudioData.BitsPerSample, channels)
+}
+
+// convertToMatch converts the file to the same format as `target`, returning
+// it unchanged if it already matches.
+func (f *File) convertToMatch(target *File) (*File, error) {
+	t := target.AudioData
+	if f.AudioData.SampleRate == t.SampleRate && f.AudioData.NumChannels == t.NumChannels && f.AudioData.BitsPerSample == t.BitsPerSample {
+		return f, nil
+	}
+	return f.ConvertTo(t.SampleRate, t.NumChannels, t.BitsPerSample)
+}
+
+// newFileFromSamples creates a new audio file from floating point samples
+// (indexed by channel first).
+func newFileFromSamples(sampleRate uint32, bitsPerSample uint16, channels [][]float64) *File {
+	wav := NewWAVFromParams(&WAVParams{
+		NumChannels:   uint16(len(channels)),
+		SampleRate:    sampleRate,
+		BitsPerSample: bitsPerSample,
+	})
+	wav.SetSamples(channels)
+	return &File{AudioData: wav}
+}
+
+// resampleBandlimited converts a single channel of audio from one sample rate
+// to another using windowed sinc interpolation.
+func resampleBandlimited(in []float64, from uint32, to uint32) []float64 {
+	if from == to || len(in) == 0 {
+		out := make([]float64, len(in))
+		copy(out, in)
+		return out
+	}
+	return resampleRatio(in, float64(from)/float64(to))
+}
+
+// resampleRatio resamples a single channel of audio so that each output
+// sample is `step` input samples apart (so a step of 2 halves the number of
+// samples). When downsampling, the sinc kernel is widened so that it also acts
+// as an anti-aliasing filter.
+func resampleRatio(in []float64, step float64) []float64 {
+	cutoff := math.Min(1, 1/step)
+	width := math.Ceil(resampleZeroCrossings / cutoff)
+
+	out := make([]float64, int(math.Round(float64(len(in))/step)))
+	for i := range out {
+		t := float64(i) * step
+		lo := int(math.Max(0, math.Ceil(t-width)))
+		hi := int(math.Min(float64(len(in)-1), math.Floor(t+width)))
+
+		sum := 0.0
+		for j := lo; j <= hi; j++ {
+			x := t - float64(j)
+			window := 0.42 + 0.5*math.Cos(math.Pi*x/width) + 0.08*math.Cos(2*math.Pi*x/width)
+			sum += in[j] * cutoff * sinc(cutoff*x) * window
+		}
+		out[i] = sum
+	}
+	return out
+}
+
+// sinc is the normalised sinc function, sin(pi x) / (pi x).
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
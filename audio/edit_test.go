@@ -0,0 +1,163 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+var errorType *errors.Error
+
+// ramp returns n samples increasing linearly from 0 to just under `amp`.
+func ramp(n int, amp float64) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = amp * float64(i) / float64(n)
+	}
+	return s
+}
+
+func TestSlice(t *testing.T) {
+	f := testutils.NewFile(1000, ramp(1000, 0.5))
+	original := f.WAVData()
+
+	s, err := f.Slice(0.25, 0.5)
+	require.Nil(t, err)
+	require.InDelta(t, 0.25, s.Duration(), 1e-9)
+	require.InDelta(t, 0.125, s.AudioData.Samples()[0][0], 1e-4)
+
+	// the original is untouched
+	require.Equal(t, original, f.WAVData())
+	require.InDelta(t, 1.0, f.Duration(), 1e-9)
+}
+
+func TestSliceSamplesStereo(t *testing.T) {
+	f := testutils.NewFile(1000, ramp(100, 0.5), ramp(100, -0.5))
+	s, err := f.SliceSamples(10, 20)
+	require.Nil(t, err)
+	require.Equal(t, uint16(2), s.AudioData.NumChannels)
+	require.Len(t, s.AudioData.Samples()[1], 10)
+	require.InDelta(t, -0.05, s.AudioData.Samples()[1][0], 1e-4)
+}
+
+func TestSliceInvalidRange(t *testing.T) {
+	f := testutils.NewFile(1000, ramp(1000, 0.5))
+	for _, r := range [][2]float64{{-0.1, 0.5}, {0.5, 0.25}, {0.5, 1.5}} {
+		_, err := f.Slice(r[0], r[1])
+		require.IsType(t, errorType, err)
+		require.Equal(t, errors.AudioFileInvalidRange, err.(*errors.Error).Code)
+	}
+}
+
+// Concatenating files of different formats converts them to the format of
+// the first one.
+func TestConcat(t *testing.T) {
+	a := testutils.NewFile(16000, testutils.Sine(440, 0.5, 16000, 16000))
+	b := testutils.NewFile(8000, testutils.Sine(440, 0.5, 8000, 8000), testutils.Sine(440, 0.5, 8000, 8000))
+
+	c, err := a.Concat(b, a)
+	require.Nil(t, err)
+	require.Equal(t, uint32(16000), c.AudioData.SampleRate)
+	require.Equal(t, uint16(1), c.AudioData.NumChannels)
+	require.InDelta(t, 3.0, c.Duration(), 1e-9)
+
+	// the converted tone is still a 440Hz tone at the same level
+	out := c.AudioData.Samples()[0]
+	expected := testutils.Sine(440, 0.5, 16000, 16000)
+	for i := 16100; i < 31900; i += 13 {
+		require.InDelta(t, expected[i-16000], out[i], 5e-3)
+	}
+	require.Equal(t, 1.0, a.Duration())
+}
+
+func TestConvertTo(t *testing.T) {
+	f := testutils.NewFile(16000, testutils.Sine(1000, 0.5, 16000, 16000))
+
+	c, err := f.ConvertTo(48000, 2, 24)
+	require.Nil(t, err)
+	require.Equal(t, uint32(48000), c.AudioData.SampleRate)
+	require.Equal(t, uint16(2), c.AudioData.NumChannels)
+	require.Equal(t, uint16(24), c.AudioData.BitsPerSample)
+	require.InDelta(t, 1.0, c.Duration(), 1e-9)
+	require.InDelta(t, 0.5/math.Sqrt2, rmsOf(c.AudioData.Samples()[1][1000:47000]), 1e-3)
+
+	// frequencies above the new Nyquist frequency are removed
+	f = testutils.NewFile(16000, testutils.Sine(6000, 0.5, 16000, 16000))
+	c, err = f.ConvertTo(8000, 1, 16)
+	require.Nil(t, err)
+	require.True(t, rmsOf(c.AudioData.Samples()[0][500:7500]) < 0.01)
+
+	_, err = f.ConvertTo(8000, 1, 12)
+	require.IsType(t, errorType, err)
+	require.Equal(t, errors.AudioFileInvalidFormat, err.(*errors.Error).Code)
+}
+
+func TestMix(t *testing.T) {
+	a := testutils.NewFile(1000, make([]float64, 1000))
+	b := testutils.NewFile(1000, ramp(1000, 0.5))
+
+	m, err := a.Mix(b, 0.5, -6)
+	require.Nil(t, err)
+	require.InDelta(t, 1.5, m.Duration(), 1e-9)
+
+	out := m.AudioData.Samples()[0]
+	require.Equal(t, 0.0, out[499])
+	require.InDelta(t, 0.5*math.Pow(10, -6.0/20)*0.5, out[1000], 1e-3)
+
+	_, err = a.Mix(b, -1, 0)
+	require.NotNil(t, err)
+}
+
+func TestFades(t *testing.T) {
+	ones := make([]float64, 1000)
+	for i := range ones {
+		ones[i] = 0.5
+	}
+	f := testutils.NewFile(1000, ones)
+
+	in := f.FadeIn(0.1).AudioData.Samples()[0]
+	require.Equal(t, 0.0, in[0])
+	require.InDelta(t, 0.25, in[50], 1e-4)
+	require.InDelta(t, 0.5, in[100], 1e-4)
+
+	out := f.FadeOut(0.1).AudioData.Samples()[0]
+	require.Equal(t, 0.0, out[999])
+	require.InDelta(t, 0.5, out[899], 1e-4)
+
+	// the original is untouched
+	require.InDelta(t, 0.5, f.AudioData.Samples()[0][0], 1e-4)
+}
+
+// An equal-power crossfade between two copies of the same uncorrelated noise
+// keeps the level roughly constant, and the length is the sum minus overlap.
+func TestCrossfade(t *testing.T) {
+	a := testutils.NewFile(1000, testutils.Noise(0.5, 1000, 7))
+	b := testutils.NewFile(1000, testutils.Noise(0.5, 1000, 8))
+
+	c, err := a.Crossfade(b, 0.2)
+	require.Nil(t, err)
+	require.InDelta(t, 1.8, c.Duration(), 1e-9)
+
+	out := c.AudioData.Samples()[0]
+	require.InDelta(t, rmsOf(out[:800]), rmsOf(out[800:1000]), 0.05)
+	require.InDelta(t, b.AudioData.Samples()[0][500], out[1300], 1e-9)
+}
+
+func TestReverse(t *testing.T) {
+	f := testutils.NewFile(1000, ramp(100, 0.5))
+	r := f.Reverse().AudioData.Samples()[0]
+	orig := f.AudioData.Samples()[0]
+	for i := range r {
+		require.Equal(t, orig[len(orig)-1-i], r[i])
+	}
+}
+
+func TestClone(t *testing.T) {
+	f := testutils.NewFile(1000, ramp(100, 0.5))
+	c := f.Clone()
+	c.ApplyGain(-6)
+	require.NotEqual(t, f.WAVData(), c.WAVData())
+}
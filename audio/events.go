@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"math"
+)
+
+// EventType is the kind of an `Event` emitted while recording.
+type EventType int
+
+const (
+	// EventLevel is emitted for every buffer of audio that is read, with the
+	// level of that buffer. It can be used to render a level meter.
+	EventLevel EventType = iota
+	// EventSpeechStarted is emitted when speech is first detected, which is
+	// when the recording proper begins.
+	EventSpeechStarted
+	// EventSpeechEnded is emitted when the recording stops because enough
+	// silence followed the speech.
+	EventSpeechEnded
+	// EventTimeout is emitted when the recording stops because it reached
+	// its maximum length.
+	EventTimeout
+	// EventError is emitted when the recording fails: either the audio device
+	// reports an error, or (with `OverflowError`) the recorded audio isn't
+	// read quickly enough, in which case the error has the code
+	// `AudioFileBufferOverflow`. The recording stops afterwards.
+	EventError
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventLevel:
+		return "Level"
+	case EventSpeechStarted:
+		return "SpeechStarted"
+	case EventSpeechEnded:
+		return "SpeechEnded"
+	case EventTimeout:
+		return "Timeout"
+	case EventError:
+		return "Error"
+	}
+	return "Unknown"
+}
+
+// Event describes something that happened while recording (see
+// `RecordingParams.OnEvent`).
+type Event struct {
+	// Type is the kind of event.
+	Type EventType
+	// Time is when the event happened, in seconds since the recording
+	// session started (including any silence before the speech).
+	Time float64
+	// Peak and RMS are the levels (in dBFS) of the most recently read buffer
+	// of audio. They are negative infinity for silence.
+	Peak float64
+	RMS  float64
+	// Speaking is whether speech has been detected in the recording yet.
+	Speaking bool
+	// Dropped is the number of buffers of audio discarded so far because
+	// they weren't consumed quickly enough (see `OverflowDropOldest`).
+	Dropped uint64
+	// Error is the error that stopped the recording, for `EventError`.
+	Error error
+}
+
+// eventEmitter builds events for a recording session and passes them to the
+// session's callback, if it has one.
+type eventEmitter struct {
+	onEvent  func(Event)
+	buffer   *RingBuffer
+	frames   int
+	speaking bool
+	peak     float64
+	rms      float64
+}
+
+// frame records that a buffer of (processed) samples was read, and emits a
+// level event for it.
+func (e *eventEmitter) frame(samples []int16) {
+	if e.onEvent == nil {
+		return
+	}
+	e.frames += len(samples)
+	peak := 0.0
+	frame := int16ToFloat(samples)
+	for _, v := range frame {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	e.peak, e.rms = linearToDB(peak), linearToDB(rms(frame))
+	e.emit(EventLevel, nil)
+}
+
+// emit sends an event of the given type, stamped with the current time and
+// levels.
+func (e *eventEmitter) emit(t EventType, err error) {
+	if t == EventSpeechStarted {
+		e.speaking = true
+	}
+	if e.onEvent == nil {
+		return
+	}
+	dropped := uint64(0)
+	if e.buffer != nil {
+		dropped = e.buffer.Dropped()
+	}
+	e.onEvent(Event{
+		Type:     t,
+		Time:     float64(e.frames) / SampleRate,
+		Peak:     e.peak,
+		RMS:      e.rms,
+		Speaking: e.speaking,
+		Dropped:  dropped,
+		Error:    err,
+	})
+}
@@ -0,0 +1,225 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMic stands in for the audio device: each call to read fills the buffer
+// with the next frame, and then with silence (or err) once they run out.
+type fakeMic struct {
+	buf    []int16
+	frames [][]int16
+	err    error
+}
+
+func (m *fakeMic) read() error {
+	if len(m.frames) == 0 {
+		if m.err != nil {
+			return m.err
+		}
+		for i := range m.buf {
+			m.buf[i] = 0
+		}
+		return nil
+	}
+	copy(m.buf, m.frames[0])
+	m.frames = m.frames[1:]
+	return nil
+}
+
+// tone returns a frame of a 500Hz sine wave, which fits a whole number of
+// periods into a buffer so that its levels are exact.
+func tone(amplitude float64) []int16 {
+	frame := make([]int16, BufSize)
+	for i := range frame {
+		frame[i] = int16(amplitude * (1 << 15) * math.Sin(2*math.Pi*500*float64(i)/SampleRate))
+	}
+	return frame
+}
+
+func frames(n int, frame []int16) [][]int16 {
+	f := make([][]int16, n)
+	for i := range f {
+		f[i] = frame
+	}
+	return f
+}
+
+// runCapture runs a recording session against the fake microphone and
+// returns the events it emitted.
+func runCapture(params *RecordingParams, mic *fakeMic, buffer *RingBuffer) ([]Event, error) {
+	var events []Event
+	params.OnEvent = func(e Event) { events = append(events, e) }
+	emitter := &eventEmitter{onEvent: params.OnEvent, buffer: buffer}
+	err := capture(params, mic.read, mic.buf, buffer, emitter)
+	finishCapture(buffer, emitter, err)
+	return events, err
+}
+
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, 0)
+	for _, e := range events {
+		if e.Type != EventLevel {
+			types = append(types, e.Type)
+		}
+	}
+	return types
+}
+
+func TestCaptureEvents(t *testing.T) {
+	mic := &fakeMic{buf: make([]int16, BufSize)}
+	mic.frames = append(frames(3, make([]int16, BufSize)), frames(5, tone(0.5))...)
+
+	params := &RecordingParams{SilenceLen: 0.2, BufferSize: 64}
+	events, err := runCapture(params, mic, NewRingBuffer(params.BufferSize, OverflowBlock))
+	require.Nil(t, err)
+
+	// 3 frames of silence, 5 of speech, then 4 of silence (> 0.2s)
+	require.Equal(t, []EventType{EventSpeechStarted, EventSpeechEnded}, eventTypes(events))
+	require.Len(t, events, 3+5+4+2)
+
+	for i, e := range events[:3] {
+		require.Equal(t, EventLevel, e.Type)
+		require.False(t, e.Speaking)
+		require.True(t, math.IsInf(e.Peak, -1))
+		require.True(t, math.IsInf(e.RMS, -1))
+		require.InDelta(t, float64((i+1)*BufSize)/SampleRate, e.Time, 1e-9)
+	}
+
+	// the first frame of speech is reported before speech is detected
+	require.Equal(t, EventLevel, events[3].Type)
+	require.False(t, events[3].Speaking)
+	require.InDelta(t, -6.02, events[3].Peak, 0.01)
+	require.InDelta(t, -9.03, events[3].RMS, 0.01)
+
+	started := events[4]
+	require.Equal(t, EventSpeechStarted, started.Type)
+	require.True(t, started.Speaking)
+	require.Equal(t, events[3].Time, started.Time)
+	require.Equal(t, events[3].Peak, started.Peak)
+
+	for _, e := range events[5:] {
+		require.True(t, e.Speaking)
+	}
+	for i := 1; i < len(events); i++ {
+		require.True(t, events[i].Time >= events[i-1].Time)
+	}
+
+	ended := events[len(events)-1]
+	require.Equal(t, EventSpeechEnded, ended.Type)
+	require.InDelta(t, float64(12*BufSize)/SampleRate, ended.Time, 1e-9)
+	require.True(t, math.IsInf(ended.Peak, -1))
+}
+
+func TestCaptureTimeout(t *testing.T) {
+	mic := &fakeMic{buf: make([]int16, BufSize)}
+	mic.frames = frames(100, tone(0.5))
+
+	params := &RecordingParams{Length: 0.5, BufferSize: 64}
+	events, err := runCapture(params, mic, NewRingBuffer(params.BufferSize, OverflowBlock))
+	require.Nil(t, err)
+	require.Equal(t, []EventType{EventSpeechStarted, EventTimeout}, eventTypes(events))
+
+	timeout := events[len(events)-1]
+	require.True(t, timeout.Time > 0.5)
+	require.True(t, timeout.Speaking)
+}
+
+// Audio that isn't read in time is dropped, and the events say how much.
+func TestCaptureDropped(t *testing.T) {
+	mic := &fakeMic{buf: make([]int16, BufSize)}
+	mic.frames = frames(10, tone(0.5))
+
+	params := &RecordingParams{SilenceLen: 0.1, BufferSize: 2}
+	buffer := NewRingBuffer(params.BufferSize, OverflowDropOldest)
+	events, err := runCapture(params, mic, buffer)
+	require.Nil(t, err)
+
+	last := events[len(events)-1]
+	require.Equal(t, EventSpeechEnded, last.Type)
+	require.Equal(t, buffer.Dropped(), last.Dropped)
+	require.True(t, last.Dropped > 0)
+	require.Equal(t, uint64(0), events[0].Dropped)
+}
+
+// A device error stops the recording whether or not speech has started, and
+// is reported both as an event and to the reader.
+func TestCaptureError(t *testing.T) {
+	failure := fmt.Errorf("device unplugged")
+
+	for _, speech := range []int{0, 2} {
+		mic := &fakeMic{buf: make([]int16, BufSize), err: failure}
+		mic.frames = append(frames(2, make([]int16, BufSize)), frames(speech, tone(0.5))...)
+
+		params := &RecordingParams{SilenceLen: 1, BufferSize: 64}
+		buffer := NewRingBuffer(params.BufferSize, OverflowBlock)
+		events, err := runCapture(params, mic, buffer)
+		require.Equal(t, failure, err)
+
+		last := events[len(events)-1]
+		require.Equal(t, EventError, last.Type)
+		require.Equal(t, failure, last.Error)
+		require.Equal(t, speech > 0, last.Speaking)
+
+		for {
+			if _, err = buffer.Read(); err != nil {
+				break
+			}
+		}
+		require.Equal(t, failure, err)
+	}
+}
+
+// Audio that isn't read in time stops the recording with an overflow error,
+// which is reported like a device error.
+func TestCaptureOverflow(t *testing.T) {
+	mic := &fakeMic{buf: make([]int16, BufSize)}
+	mic.frames = frames(10, tone(0.5))
+
+	params := &RecordingParams{SilenceLen: 1, BufferSize: 2}
+	events, err := runCapture(params, mic, NewRingBuffer(params.BufferSize, OverflowError))
+	require.NotNil(t, err)
+	require.Equal(t, errors.AudioFileBufferOverflow, err.(*errors.Error).Code)
+
+	last := events[len(events)-1]
+	require.Equal(t, EventError, last.Type)
+	require.Equal(t, err, last.Error)
+}
+
+// Closing the buffer stops the recording even if nobody has spoken yet.
+func TestCaptureClosedDuringSilence(t *testing.T) {
+	mic := &fakeMic{buf: make([]int16, BufSize)}
+	params := &RecordingParams{SilenceLen: 1, BufferSize: 64}
+	buffer := NewRingBuffer(params.BufferSize, OverflowBlock)
+
+	levels := make(chan struct{}, 1)
+	params.OnEvent = func(e Event) {
+		select {
+		case levels <- struct{}{}:
+		default:
+		}
+	}
+	emitter := &eventEmitter{onEvent: params.OnEvent, buffer: buffer}
+
+	done := make(chan error)
+	go func() {
+		err := capture(params, mic.read, mic.buf, buffer, emitter)
+		finishCapture(buffer, emitter, err)
+		done <- err
+	}()
+
+	<-levels
+	buffer.Close(nil)
+	select {
+	case err := <-done:
+		require.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("recording didn't stop after the buffer was closed")
+	}
+}
@@ -0,0 +1,17 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventTypeString(t *testing.T) {
+	require.Equal(t, "Level", audio.EventLevel.String())
+	require.Equal(t, "SpeechStarted", audio.EventSpeechStarted.String())
+	require.Equal(t, "SpeechEnded", audio.EventSpeechEnded.String())
+	require.Equal(t, "Timeout", audio.EventTimeout.String())
+	require.Equal(t, "Error", audio.EventError.String())
+	require.Equal(t, "Unknown", audio.EventType(100).String())
+}
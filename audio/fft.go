@@ -0,0 +1,77 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// fft computes the discrete Fourier transform of `x` in place using the
+// iterative radix-2 Cooley-Tukey algorithm. The length of `x` must be a power
+// of two.
+func fft(x []complex128) {
+	fftDir(x, -1)
+}
+
+// ifft computes the inverse discrete Fourier transform of `x` in place
+// (including the 1/N scaling). The length of `x` must be a power of two.
+func ifft(x []complex128) {
+	fftDir(x, 1)
+	n := complex(float64(len(x)), 0)
+	for i := range x {
+		x[i] /= n
+	}
+}
+
+// fftDir performs the transform, with the sign of the exponent given by
+// `dir` (-1 for forward, 1 for inverse).
+func fftDir(x []complex128, dir float64) {
+	n := len(x)
+	if n < 2 {
+		return
+	}
+
+	// bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	// butterflies
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, dir*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a := x[start+k]
+				b := x[start+k+size/2] * w
+				x[start+k] = a + b
+				x[start+k+size/2] = a - b
+				w *= step
+			}
+		}
+	}
+}
+
+// nextPow2 returns the smallest power of two that is at least n.
+func nextPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// hann returns a periodic Hann window of length n.
+func hann(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return w
+}
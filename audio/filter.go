@@ -0,0 +1,357 @@
+package audio
+
+import (
+	"math"
+)
+
+const (
+	// DefaultPreEmphasis is the pre-emphasis coefficient commonly used for
+	// speech processing.
+	DefaultPreEmphasis = 0.97
+	// TelephonyLowCutoff and TelephonyHighCutoff are the edges (in Hz) of
+	// the traditional telephone voice band.
+	TelephonyLowCutoff  = 300.0
+	TelephonyHighCutoff = 3400.0
+)
+
+// Biquad is a second-order IIR filter (in direct form I). The constructors
+// below design the common filter types following Robert Bristow-Johnson's
+// "Audio EQ Cookbook". It is a Processor, so filters can be chained together,
+// applied to a file with `File.Apply`, or inserted in the recording pipeline.
+type Biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// NewBiquad creates a biquad filter with the given coefficients, where the
+// transfer function is (b0 + b1 z^-1 + b2 z^-2) / (a0 + a1 z^-1 + a2 z^-2).
+func NewBiquad(b0, b1, b2, a0, a1, a2 float64) *Biquad {
+	return &Biquad{b0: b0 / a0, b1: b1 / a0, b2: b2 / a0, a1: a1 / a0, a2: a2 / a0}
+}
+
+// NewLowPass creates a low-pass filter with the given cutoff frequency (Hz)
+// and Q (use 1/sqrt(2) for a maximally flat response).
+func NewLowPass(sampleRate uint32, freq float64, q float64) *Biquad {
+	cos, alpha := rbjParams(sampleRate, freq, q)
+	return NewBiquad((1-cos)/2, 1-cos, (1-cos)/2, 1+alpha, -2*cos, 1-alpha)
+}
+
+// NewHighPass creates a high-pass filter with the given cutoff frequency (Hz)
+// and Q (use 1/sqrt(2) for a maximally flat response).
+func NewHighPass(sampleRate uint32, freq float64, q float64) *Biquad {
+	cos, alpha := rbjParams(sampleRate, freq, q)
+	return NewBiquad((1+cos)/2, -(1 + cos), (1+cos)/2, 1+alpha, -2*cos, 1-alpha)
+}
+
+// NewBandPass creates a band-pass filter centred on the given frequency (Hz)
+// with a peak gain of 0dB. Higher values of Q give a narrower band.
+func NewBandPass(sampleRate uint32, freq float64, q float64) *Biquad {
+	cos, alpha := rbjParams(sampleRate, freq, q)
+	return NewBiquad(alpha, 0, -alpha, 1+alpha, -2*cos, 1-alpha)
+}
+
+// NewNotch creates a filter that rejects a narrow band around the given
+// frequency (Hz), such as mains hum. Higher values of Q give a narrower notch.
+func NewNotch(sampleRate uint32, freq float64, q float64) *Biquad {
+	cos, alpha := rbjParams(sampleRate, freq, q)
+	return NewBiquad(1, -2*cos, 1, 1+alpha, -2*cos, 1-alpha)
+}
+
+// NewPeaking creates a peaking EQ filter that boosts (or cuts, if `gain` is
+// negative) a band around the given frequency (Hz) by `gain` dB.
+func NewPeaking(sampleRate uint32, freq float64, q float64, gain float64) *Biquad {
+	a := math.Pow(10, gain/40)
+	cos, alpha := rbjParams(sampleRate, freq, q)
+	return NewBiquad(1+alpha*a, -2*cos, 1-alpha*a, 1+alpha/a, -2*cos, 1-alpha/a)
+}
+
+// NewLowShelf creates a filter that boosts (or cuts, if `gain` is negative)
+// frequencies below the given frequency (Hz) by `gain` dB.
+func NewLowShelf(sampleRate uint32, freq float64, q float64, gain float64) *Biquad {
+	a := math.Pow(10, gain/40)
+	cos, alpha := rbjParams(sampleRate, freq, q)
+	sq := 2 * math.Sqrt(a) * alpha
+	return NewBiquad(
+		a*((a+1)-(a-1)*cos+sq),
+		2*a*((a-1)-(a+1)*cos),
+		a*((a+1)-(a-1)*cos-sq),
+		(a+1)+(a-1)*cos+sq,
+		-2*((a-1)+(a+1)*cos),
+		(a+1)+(a-1)*cos-sq,
+	)
+}
+
+// NewHighShelf creates a filter that boosts (or cuts, if `gain` is negative)
+// frequencies above the given frequency (Hz) by `gain` dB.
+func NewHighShelf(sampleRate uint32, freq float64, q float64, gain float64) *Biquad {
+	a := math.Pow(10, gain/40)
+	cos, alpha := rbjParams(sampleRate, freq, q)
+	sq := 2 * math.Sqrt(a) * alpha
+	return NewBiquad(
+		a*((a+1)+(a-1)*cos+sq),
+		-2*a*((a-1)+(a+1)*cos),
+		a*((a+1)+(a-1)*cos-sq),
+		(a+1)-(a-1)*cos+sq,
+		2*((a-1)-(a+1)*cos),
+		(a+1)-(a-1)*cos-sq,
+	)
+}
+
+// rbjParams computes the intermediate values shared by the cookbook designs.
+func rbjParams(sampleRate uint32, freq float64, q float64) (cos float64, alpha float64) {
+	w0 := 2 * math.Pi * freq / float64(sampleRate)
+	return math.Cos(w0), math.Sin(w0) / (2 * q)
+}
+
+// Process filters a frame of samples in place.
+func (b *Biquad) Process(frame []float64) []float64 {
+	for i, x := range frame {
+		y := b.b0*x + b.b1*b.x1 + b.b2*b.x2 - b.a1*b.y1 - b.a2*b.y2
+		b.x2, b.x1 = b.x1, x
+		b.y2, b.y1 = b.y1, y
+		frame[i] = y
+	}
+	return frame
+}
+
+// Reset clears the filter's memory of previous samples.
+func (b *Biquad) Reset() {
+	b.x1, b.x2, b.y1, b.y2 = 0, 0, 0, 0
+}
+
+// NewButterworthLowPass creates a Butterworth low-pass filter of the given
+// order with the given cutoff frequency (Hz), as a chain of biquads. Higher
+// orders give a steeper roll-off.
+func NewButterworthLowPass(sampleRate uint32, freq float64, order int) *Chain {
+	return butterworth(sampleRate, freq, order, NewLowPass, func(k float64) *Biquad {
+		return NewBiquad(k, k, 0, 1+k, k-1, 0)
+	})
+}
+
+// NewButterworthHighPass creates a Butterworth high-pass filter of the given
+// order with the given cutoff frequency (Hz), as a chain of biquads. Higher
+// orders give a steeper roll-off.
+func NewButterworthHighPass(sampleRate uint32, freq float64, order int) *Chain {
+	return butterworth(sampleRate, freq, order, NewHighPass, func(k float64) *Biquad {
+		return NewBiquad(1, -1, 0, 1+k, k-1, 0)
+	})
+}
+
+// NewButterworthBandPass creates a band-pass filter between the given
+// frequencies (Hz) by chaining Butterworth high-pass and low-pass filters of
+// the given order.
+func NewButterworthBandPass(sampleRate uint32, low float64, high float64, order int) *Chain {
+	return NewChain(
+		NewButterworthHighPass(sampleRate, low, order),
+		NewButterworthLowPass(sampleRate, high, order),
+	)
+}
+
+// NewTelephonyFilter creates a band-pass filter that limits audio to the
+// telephone voice band (300Hz to 3400Hz).
+func NewTelephonyFilter(sampleRate uint32) *Chain {
+	return NewButterworthBandPass(sampleRate, TelephonyLowCutoff, TelephonyHighCutoff, 4)
+}
+
+// butterworth builds a Butterworth filter out of second-order sections with
+// the appropriate Q values, plus a first-order section for odd orders.
+func butterworth(sampleRate uint32, freq float64, order int, section func(uint32, float64, float64) *Biquad, firstOrder func(float64) *Biquad) *Chain {
+	if order < 1 {
+		order = 1
+	}
+
+	// each pair of complex conjugate poles makes up one section, whose Q
+	// depends on the angle of the poles from the negative real axis
+	chain := NewChain()
+	for k := 0; k < order/2; k++ {
+		angle := math.Pi * float64(2*k+1) / float64(2*order)
+		if order%2 == 1 {
+			angle = math.Pi * float64(k+1) / float64(order)
+		}
+		q := 1 / (2 * math.Cos(angle))
+		chain.Processors = append(chain.Processors, section(sampleRate, freq, q))
+	}
+	if order%2 == 1 {
+		k := math.Tan(math.Pi * freq / float64(sampleRate))
+		chain.Processors = append(chain.Processors, firstOrder(k))
+	}
+	return chain
+}
+
+// FIR is a finite impulse response filter. FIR filters designed with the
+// constructors below have linear phase, and therefore delay their output by
+// `Delay()` samples (which `File.Apply` compensates for).
+type FIR struct {
+	coeffs []float64
+	// history is a double-length circular buffer of the most recent inputs
+	// so that a contiguous window (newest first) is always available
+	history []float64
+	pos     int
+}
+
+// NewFIR creates an FIR filter with the given coefficients (impulse response).
+func NewFIR(coeffs []float64) *FIR {
+	c := make([]float64, len(coeffs))
+	copy(c, coeffs)
+	return &FIR{coeffs: c, history: make([]float64, 2*len(c))}
+}
+
+// NewFIRLowPass designs a linear phase low-pass FIR filter with the given
+// cutoff frequency (Hz) and number of taps, using the windowed-sinc method
+// with a Blackman window. More taps give a sharper transition. An odd number
+// of taps is used so that the delay is a whole number of samples.
+func NewFIRLowPass(sampleRate uint32, freq float64, taps int) *FIR {
+	return NewFIR(sincLowPass(freq/float64(sampleRate), oddTaps(taps)))
+}
+
+// NewFIRHighPass designs a linear phase high-pass FIR filter with the given
+// cutoff frequency (Hz) and number of taps (see `NewFIRLowPass`).
+func NewFIRHighPass(sampleRate uint32, freq float64, taps int) *FIR {
+	// spectral inversion of the low-pass filter
+	h := sincLowPass(freq/float64(sampleRate), oddTaps(taps))
+	for i := range h {
+		h[i] = -h[i]
+	}
+	h[len(h)/2]++
+	return NewFIR(h)
+}
+
+// NewFIRBandPass designs a linear phase band-pass FIR filter between the given
+// frequencies (Hz) with the given number of taps (see `NewFIRLowPass`).
+func NewFIRBandPass(sampleRate uint32, low float64, high float64, taps int) *FIR {
+	// difference of two low-pass filters
+	n := oddTaps(taps)
+	h := sincLowPass(high/float64(sampleRate), n)
+	l := sincLowPass(low/float64(sampleRate), n)
+	for i := range h {
+		h[i] -= l[i]
+	}
+	return NewFIR(h)
+}
+
+// oddTaps rounds the number of taps up to the next odd number.
+func oddTaps(taps int) int {
+	if taps < 1 {
+		return 1
+	}
+	return taps | 1
+}
+
+// sincLowPass designs a Blackman-windowed sinc low-pass filter with the given
+// cutoff (as a fraction of the sample rate) and normalises it to unity gain
+// at DC.
+func sincLowPass(cutoff float64, taps int) []float64 {
+	h := make([]float64, taps)
+	m := float64(taps - 1)
+	sum := 0.0
+	for i := range h {
+		x := float64(i) - m/2
+		if x == 0 {
+			h[i] = 2 * cutoff
+		} else {
+			h[i] = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		if taps > 1 {
+			h[i] *= 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/m) + 0.08*math.Cos(4*math.Pi*float64(i)/m)
+		}
+		sum += h[i]
+	}
+	for i := range h {
+		h[i] /= sum
+	}
+	return h
+}
+
+// Delay returns the number of samples by which the output lags the input
+// (half the length of the filter).
+func (f *FIR) Delay() int {
+	return (len(f.coeffs) - 1) / 2
+}
+
+// Process filters a frame of samples in place.
+func (f *FIR) Process(frame []float64) []float64 {
+	n := len(f.coeffs)
+	for i, x := range frame {
+		f.pos = (f.pos + n - 1) % n
+		f.history[f.pos] = x
+		f.history[f.pos+n] = x
+
+		y := 0.0
+		for k, c := range f.coeffs {
+			y += c * f.history[f.pos+k]
+		}
+		frame[i] = y
+	}
+	return frame
+}
+
+// Reset clears the filter's memory of previous samples.
+func (f *FIR) Reset() {
+	for i := range f.history {
+		f.history[i] = 0
+	}
+	f.pos = 0
+}
+
+// PreEmphasis is a first-order high-pass filter (y[n] = x[n] - a*x[n-1]) that
+// boosts high frequencies, which is commonly done before speech analysis.
+type PreEmphasis struct {
+	// Coefficient is the amount of pre-emphasis (usually close to 1).
+	Coefficient float64
+
+	prev float64
+}
+
+// NewPreEmphasis creates a pre-emphasis filter with the given coefficient (see
+// `DefaultPreEmphasis`).
+func NewPreEmphasis(coefficient float64) *PreEmphasis {
+	return &PreEmphasis{Coefficient: coefficient}
+}
+
+// Process filters a frame of samples in place.
+func (p *PreEmphasis) Process(frame []float64) []float64 {
+	for i, x := range frame {
+		frame[i] = x - p.Coefficient*p.prev
+		p.prev = x
+	}
+	return frame
+}
+
+// Reset clears the filter's memory of the previous sample.
+func (p *PreEmphasis) Reset() {
+	p.prev = 0
+}
+
+// Chain is a Processor that runs several processors one after another. It can
+// be used to build more complex filters out of simpler ones.
+type Chain struct {
+	Processors []Processor
+}
+
+// NewChain creates a chain of the given processors.
+func NewChain(processors ...Processor) *Chain {
+	return &Chain{Processors: processors}
+}
+
+// Delay returns the total delay of the processors in the chain.
+func (c *Chain) Delay() int {
+	delay := 0
+	for _, p := range c.Processors {
+		if d, ok := p.(delayer); ok {
+			delay += d.Delay()
+		}
+	}
+	return delay
+}
+
+// Process runs a frame of samples through each processor in turn.
+func (c *Chain) Process(frame []float64) []float64 {
+	return process(frame, c.Processors)
+}
+
+// Reset resets every processor in the chain.
+func (c *Chain) Reset() {
+	for _, p := range c.Processors {
+		p.Reset()
+	}
+}
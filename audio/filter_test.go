@@ -0,0 +1,130 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// response measures the steady-state gain (in dB) of a processor at the given
+// frequency by filtering a sine wave and comparing levels over the second
+// half.
+func response(p audio.Processor, freq float64) float64 {
+	n := 16000
+	in := testutils.Sine(freq, 0.5, 16000, n)
+	out := p.Process(append([]float64(nil), in...))
+	p.Reset()
+	return 20 * math.Log10(rmsOf(out[n/2:])/rmsOf(in[n/2:]))
+}
+
+func TestBiquadLowPass(t *testing.T) {
+	f := audio.NewLowPass(16000, 1000, 1/math.Sqrt2)
+	require.InDelta(t, 0, response(f, 100), 0.1)
+	require.InDelta(t, -3, response(f, 1000), 0.1)
+	require.True(t, response(f, 6000) < -25)
+}
+
+func TestBiquadHighPass(t *testing.T) {
+	f := audio.NewHighPass(16000, 1000, 1/math.Sqrt2)
+	require.True(t, response(f, 100) < -35)
+	require.InDelta(t, -3, response(f, 1000), 0.1)
+	require.InDelta(t, 0, response(f, 6000), 0.1)
+}
+
+func TestBiquadBandPassAndNotch(t *testing.T) {
+	bp := audio.NewBandPass(16000, 1000, 2)
+	require.InDelta(t, 0, response(bp, 1000), 0.1)
+	require.True(t, response(bp, 100) < -20)
+	require.True(t, response(bp, 6000) < -15)
+
+	notch := audio.NewNotch(16000, 60, 10)
+	require.True(t, response(notch, 60) < -30)
+	require.InDelta(t, 0, response(notch, 1000), 0.1)
+}
+
+func TestBiquadEQ(t *testing.T) {
+	require.InDelta(t, 6, response(audio.NewPeaking(16000, 1000, 1, 6), 1000), 0.1)
+	require.InDelta(t, -6, response(audio.NewLowShelf(16000, 500, 1/math.Sqrt2, -6), 50), 0.1)
+	require.InDelta(t, 6, response(audio.NewHighShelf(16000, 2000, 1/math.Sqrt2, 6), 7000), 0.2)
+}
+
+// Butterworth filters are -3dB at the cutoff regardless of order, and follow
+// the (frequency-warped) Butterworth magnitude response elsewhere.
+func TestButterworth(t *testing.T) {
+	warp := func(f float64) float64 { return math.Tan(math.Pi * f / 16000) }
+	for _, order := range []int{1, 2, 3, 4, 6} {
+		lp := audio.NewButterworthLowPass(16000, 500, order)
+		expected := -10 * math.Log10(1+math.Pow(warp(2000)/warp(500), float64(2*order)))
+		require.InDelta(t, -3, response(lp, 500), 0.1, "order %d", order)
+		require.InDelta(t, expected, response(lp, 2000), 0.2, "order %d", order)
+
+		hp := audio.NewButterworthHighPass(16000, 500, order)
+		require.InDelta(t, -3, response(hp, 500), 0.1, "order %d", order)
+		require.InDelta(t, 0, response(hp, 4000), 0.1, "order %d", order)
+	}
+}
+
+func TestTelephonyFilter(t *testing.T) {
+	f := audio.NewTelephonyFilter(16000)
+	require.InDelta(t, 0, response(f, 1000), 0.2)
+	require.True(t, response(f, 50) < -40)
+	require.True(t, response(f, 7000) < -25)
+}
+
+func TestFIR(t *testing.T) {
+	lp := audio.NewFIRLowPass(16000, 1000, 101)
+	require.Equal(t, 50, lp.Delay())
+	require.InDelta(t, 0, response(lp, 200), 0.1)
+	require.True(t, response(lp, 3000) < -50)
+
+	hp := audio.NewFIRHighPass(16000, 1000, 100)
+	require.Equal(t, 50, hp.Delay())
+	require.True(t, response(hp, 200) < -50)
+	require.InDelta(t, 0, response(hp, 3000), 0.1)
+
+	bp := audio.NewFIRBandPass(16000, 300, 3400, 101)
+	require.InDelta(t, 0, response(bp, 1000), 0.1)
+	require.True(t, response(bp, 7000) < -50)
+}
+
+// Linear phase FIR filters are delay-compensated when applied to a file, so
+// frequencies in the pass band come out aligned with the input.
+func TestFIRApplyCompensatesDelay(t *testing.T) {
+	in := testutils.Sine(200, 0.5, 16000, 16000)
+	f := testutils.NewFile(16000, in)
+	f.Apply(audio.NewFIRLowPass(16000, 1000, 101))
+
+	out := f.AudioData.Samples()[0]
+	require.Len(t, out, len(in))
+	for i := 1000; i < len(in)-1000; i += 37 {
+		require.InDelta(t, in[i], out[i], 2e-3)
+	}
+}
+
+func TestPreEmphasis(t *testing.T) {
+	p := audio.NewPreEmphasis(audio.DefaultPreEmphasis)
+	out := p.Process([]float64{1, 1, 0.5})
+	require.InDeltaSlice(t, []float64{1, 0.03, -0.47}, out, 1e-12)
+
+	// state carries over between frames
+	out = p.Process([]float64{0})
+	require.InDeltaSlice(t, []float64{-0.485}, out, 1e-12)
+}
+
+// Processing frame by frame should give the same result as processing the
+// whole signal at once.
+func TestChainFrames(t *testing.T) {
+	in := testutils.Noise(0.5, 8000, 6)
+	whole := audio.NewChain(audio.NewButterworthHighPass(16000, 80, 2), audio.NewPreEmphasis(audio.DefaultPreEmphasis))
+	expected := whole.Process(append([]float64(nil), in...))
+
+	framed := audio.NewChain(audio.NewButterworthHighPass(16000, 80, 2), audio.NewPreEmphasis(audio.DefaultPreEmphasis))
+	out := make([]float64, 0, len(in))
+	for i := 0; i < len(in); i += 160 {
+		out = append(out, framed.Process(append([]float64(nil), in[i:i+160]...))...)
+	}
+	require.InDeltaSlice(t, expected, out, 1e-12)
+}
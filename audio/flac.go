@@ -0,0 +1,403 @@
+package audio
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+const (
+	// flacBlockSize is the number of samples (per channel) in each FLAC
+	// frame.
+	flacBlockSize = 4096
+	// flacMaxFixedOrder is the highest order of the fixed predictors.
+	flacMaxFixedOrder = 4
+	// flacMaxRiceParam is the highest Rice parameter that can be encoded
+	// without an escape code.
+	flacMaxRiceParam = 14
+	// flacStreamInfoOffset is where the STREAMINFO block starts (after the
+	// "fLaC" marker and the block header).
+	flacStreamInfoOffset = 8
+)
+
+// FLACWriter encodes 16-bit PCM audio as FLAC (lossless compression, usually
+// around half the size of a WAV file) and writes it as it is received. Each
+// block of samples is compressed with the best of FLAC's fixed linear
+// predictors. The total length in the header is updated whenever the writer
+// is flushed, and the checksum of the audio is only filled in when it is
+// closed (FLAC allows it to be unknown), so a partially written file can
+// still be decoded.
+type FLACWriter struct {
+	w           io.WriteSeeker
+	numChannels int
+	sampleRate  uint32
+
+	pending  [][]int32
+	frames   uint64
+	samples  uint64
+	minFrame int
+	maxFrame int
+	md5      hash.Hash
+	size     int64
+}
+
+// NewFLACWriter writes a FLAC header for audio with the given format to `w`
+// and returns a writer for the audio data. Only 16-bit audio is supported,
+// with 1 to 8 channels.
+func NewFLACWriter(w io.WriteSeeker, sampleRate uint32, numChannels uint16, bitsPerSample uint16) (*FLACWriter, error) {
+	if bitsPerSample != 16 || numChannels < 1 || numChannels > 8 || sampleRate == 0 || sampleRate >= 1<<20 {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidFormat, fmt.Sprintf("FLAC output supports 16-bit audio with 1 to 8 channels, but %dHz, %d channel(s), %d bits per sample were requested.", sampleRate, numChannels, bitsPerSample))
+	}
+
+	f := &FLACWriter{
+		w:           w,
+		numChannels: int(numChannels),
+		sampleRate:  sampleRate,
+		pending:     make([][]int32, numChannels),
+		md5:         md5.New(),
+	}
+	header := append([]byte("fLaC"), 0x80, 0, 0, 34)
+	header = append(header, f.streamInfo()...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	f.size = int64(len(header))
+	return f, nil
+}
+
+// Write adds interleaved 16-bit little-endian PCM data. Complete blocks are
+// encoded and written immediately, and the rest is held until more data
+// arrives or the writer is flushed.
+func (f *FLACWriter) Write(p []byte) (int, error) {
+	frameSize := 2 * f.numChannels
+	if len(p)%frameSize != 0 {
+		return 0, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidFormat, fmt.Sprintf("Wrote %d bytes, which isn't a whole number of %d byte samples.", len(p), frameSize))
+	}
+	f.md5.Write(p)
+
+	for i := 0; i < len(p); i += frameSize {
+		for c := 0; c < f.numChannels; c++ {
+			v := int16(binary.LittleEndian.Uint16(p[i+2*c:]))
+			f.pending[c] = append(f.pending[c], int32(v))
+		}
+		if len(f.pending[0]) == flacBlockSize {
+			if err := f.writeFrame(); err != nil {
+				return i, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// Flush updates the header with the length of the audio written so far, and
+// syncs it to disk if the underlying writer is a file. Every block but the
+// last must be full, so up to one block of samples (`flacBlockSize`) is held
+// back until more audio arrives or the writer is closed.
+func (f *FLACWriter) Flush() error {
+	if err := f.updateHeader(false); err != nil {
+		return err
+	}
+	return syncFile(f.w)
+}
+
+// Close encodes any held samples as a final (shorter) block and completes the
+// header, including the checksum of the audio. It doesn't close the
+// underlying writer.
+func (f *FLACWriter) Close() error {
+	if len(f.pending[0]) > 0 {
+		if err := f.writeFrame(); err != nil {
+			return err
+		}
+	}
+	return f.updateHeader(true)
+}
+
+// Size returns the number of bytes written so far.
+func (f *FLACWriter) Size() int64 {
+	return f.size
+}
+
+// updateHeader rewrites the STREAMINFO block.
+func (f *FLACWriter) updateHeader(final bool) error {
+	info := f.streamInfo()
+	if final {
+		copy(info[18:], f.md5.Sum(nil))
+	}
+	return writeAt(f.w, info, flacStreamInfoOffset)
+}
+
+// streamInfo builds the STREAMINFO metadata block. The checksum is left
+// blank (meaning unknown).
+func (f *FLACWriter) streamInfo() []byte {
+	bw := &bitWriter{}
+	bw.write(flacBlockSize, 16)
+	bw.write(flacBlockSize, 16)
+	bw.write(uint64(f.minFrame), 24)
+	bw.write(uint64(f.maxFrame), 24)
+	bw.write(uint64(f.sampleRate), 20)
+	bw.write(uint64(f.numChannels-1), 3)
+	bw.write(15, 5)
+	bw.write(f.samples, 36)
+	bw.align()
+	return append(bw.bytes(), make([]byte, 16)...)
+}
+
+// writeFrame encodes the pending samples as a frame.
+func (f *FLACWriter) writeFrame() error {
+	n := len(f.pending[0])
+	bw := &bitWriter{}
+
+	// header: sync code, fixed blocksize, block size and channels (sample
+	// rate and size come from STREAMINFO), then the frame number
+	bw.write(0x3FFE, 14)
+	bw.write(0, 2)
+	blockCode := uint64(7)
+	if n == flacBlockSize {
+		blockCode = 12
+	}
+	bw.write(blockCode, 4)
+	bw.write(0, 4)
+	bw.write(uint64(f.numChannels-1), 4)
+	bw.write(0, 4)
+	writeUTF8(bw, f.frames)
+	if blockCode == 7 {
+		bw.write(uint64(n-1), 16)
+	}
+	bw.write(uint64(crc8(bw.bytes())), 8)
+
+	for _, ch := range f.pending {
+		encodeSubframe(bw, ch)
+	}
+	bw.align()
+	bw.write(uint64(crc16(bw.bytes())), 16)
+
+	frame := bw.bytes()
+	if _, err := f.w.Write(frame); err != nil {
+		return err
+	}
+
+	f.size += int64(len(frame))
+	f.frames++
+	f.samples += uint64(n)
+	if f.minFrame == 0 || len(frame) < f.minFrame {
+		f.minFrame = len(frame)
+	}
+	if len(frame) > f.maxFrame {
+		f.maxFrame = len(frame)
+	}
+	for c := range f.pending {
+		f.pending[c] = f.pending[c][:0]
+	}
+	return nil
+}
+
+// encodeSubframe writes a channel of a frame using whichever of a constant,
+// fixed prediction or verbatim subframe is smallest.
+func encodeSubframe(bw *bitWriter, samples []int32) {
+	constant := true
+	for _, v := range samples {
+		if v != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		bw.write(0, 8)
+		bw.writeSigned(int64(samples[0]), 16)
+		return
+	}
+
+	// pick the predictor with the smallest residual
+	bestOrder, bestResidual, bestSum := 0, []int64(nil), uint64(math.MaxUint64)
+	for order := 0; order <= flacMaxFixedOrder && order < len(samples); order++ {
+		residual := fixedResidual(samples, order)
+		sum := uint64(0)
+		for _, r := range residual {
+			sum += uint64(abs64(r))
+		}
+		if sum < bestSum {
+			bestOrder, bestResidual, bestSum = order, residual, sum
+		}
+	}
+
+	// fall back to verbatim if the (estimated) size of the residual is no
+	// better
+	param := riceParam(bestSum, len(bestResidual))
+	riceBits := uint64(len(bestResidual))*uint64(param+1) + 2*bestSum>>uint(param)
+	if riceBits >= uint64(16*len(samples)) {
+		// verbatim
+		bw.write(0x02, 8)
+		for _, v := range samples {
+			bw.writeSigned(int64(v), 16)
+		}
+		return
+	}
+
+	bw.write(uint64(0x08|bestOrder)<<1, 8)
+	for _, v := range samples[:bestOrder] {
+		bw.writeSigned(int64(v), 16)
+	}
+	// Rice coding with a single partition
+	bw.write(0, 2)
+	bw.write(0, 4)
+	bw.write(uint64(param), 4)
+	for _, r := range bestResidual {
+		u := uint64((r << 1) ^ (r >> 63))
+		bw.writeUnary(u >> uint(param))
+		bw.write(u&(1<<uint(param)-1), param)
+	}
+}
+
+// fixedResidual returns the residual of FLAC's fixed polynomial predictor of
+// the given order (the order'th difference of the samples).
+func fixedResidual(samples []int32, order int) []int64 {
+	residual := make([]int64, 0, len(samples)-order)
+	for i := order; i < len(samples); i++ {
+		s := func(j int) int64 { return int64(samples[i-j]) }
+		var r int64
+		switch order {
+		case 0:
+			r = s(0)
+		case 1:
+			r = s(0) - s(1)
+		case 2:
+			r = s(0) - 2*s(1) + s(2)
+		case 3:
+			r = s(0) - 3*s(1) + 3*s(2) - s(3)
+		case 4:
+			r = s(0) - 4*s(1) + 6*s(2) - 4*s(3) + s(4)
+		}
+		residual = append(residual, r)
+	}
+	return residual
+}
+
+// riceParam estimates the best Rice parameter for residuals with the given
+// sum of absolute values.
+func riceParam(sum uint64, n int) int {
+	if n == 0 || sum == 0 {
+		return 0
+	}
+	param := 0
+	for param < flacMaxRiceParam && uint64(n)<<uint(param+1) < sum {
+		param++
+	}
+	return param
+}
+
+// writeUTF8 writes a frame number with FLAC's extended UTF-8 encoding.
+func writeUTF8(bw *bitWriter, v uint64) {
+	if v < 0x80 {
+		bw.write(v, 8)
+		return
+	}
+	// number of continuation bytes needed: each holds 6 bits, and the first
+	// byte holds 6-n
+	n := 1
+	for v>>uint(6*n) >= 1<<uint(6-n) {
+		n++
+	}
+	lead := uint64(0xFF<<uint(7-n)) & 0xFF
+	bw.write(lead|v>>uint(6*n), 8)
+	for i := n - 1; i >= 0; i-- {
+		bw.write(0x80|(v>>uint(6*i))&0x3F, 8)
+	}
+}
+
+// crc8 computes the CRC-8 (polynomial 0x07) used for FLAC frame headers.
+func crc8(data []byte) uint8 {
+	crc := uint8(0)
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 computes the CRC-16 (polynomial 0x8005) used for FLAC frames.
+func crc16(data []byte) uint16 {
+	crc := uint16(0)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// abs64 returns the absolute value of v.
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// bitWriter packs values into a byte slice, most significant bit first.
+type bitWriter struct {
+	buf   []byte
+	cur   uint64
+	nbits uint
+}
+
+// write appends the lowest `n` bits of v.
+func (b *bitWriter) write(v uint64, n int) {
+	for n > 0 {
+		take := n
+		if free := int(64 - b.nbits); take > free {
+			take = free
+		}
+		n -= take
+		bits := (v >> uint(n)) & (1<<uint(take) - 1)
+		if take == 64 {
+			bits = v
+		}
+		b.cur = b.cur<<uint(take) | bits
+		b.nbits += uint(take)
+		for b.nbits >= 8 {
+			b.nbits -= 8
+			b.buf = append(b.buf, byte(b.cur>>b.nbits))
+		}
+	}
+}
+
+// writeSigned appends v as an `n` bit two's complement number.
+func (b *bitWriter) writeSigned(v int64, n int) {
+	b.write(uint64(v)&(1<<uint(n)-1), n)
+}
+
+// writeUnary appends v zeros followed by a one.
+func (b *bitWriter) writeUnary(v uint64) {
+	for ; v >= 32; v -= 32 {
+		b.write(0, 32)
+	}
+	b.write(1, int(v)+1)
+}
+
+// align pads with zeros up to the next byte boundary.
+func (b *bitWriter) align() {
+	if b.nbits > 0 {
+		b.write(0, int(8-b.nbits))
+	}
+}
+
+// bytes returns the complete bytes written so far.
+func (b *bitWriter) bytes() []byte {
+	return b.buf
+}
@@ -0,0 +1,277 @@
+package audio_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// memFile is an in-memory io.WriteSeeker.
+type memFile struct {
+	data []byte
+	pos  int
+}
+
+func (m *memFile) Write(p []byte) (int, error) {
+	if end := m.pos + len(p); end > len(m.data) {
+		m.data = append(m.data, make([]byte, end-len(m.data))...)
+	}
+	copy(m.data[m.pos:], p)
+	m.pos += len(p)
+	return len(p), nil
+}
+
+func (m *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		m.pos = int(offset)
+	case io.SeekCurrent:
+		m.pos += int(offset)
+	case io.SeekEnd:
+		m.pos = len(m.data) + int(offset)
+	}
+	return int64(m.pos), nil
+}
+
+// flacStream is what decodeFLAC found in a FLAC stream.
+type flacStream struct {
+	sampleRate   uint32
+	channels     int
+	totalSamples uint64
+	md5          []byte
+	// samples holds the decoded samples of each channel
+	samples [][]int32
+}
+
+// bitReader reads big-endian bit fields.
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (b *bitReader) read(n int) uint64 {
+	v := uint64(0)
+	for i := 0; i < n; i++ {
+		bit := (b.data[b.pos/8] >> uint(7-b.pos%8)) & 1
+		v = v<<1 | uint64(bit)
+		b.pos++
+	}
+	return v
+}
+
+func (b *bitReader) readSigned(n int) int64 {
+	v := b.read(n)
+	if v&(1<<uint(n-1)) != 0 {
+		return int64(v) - int64(1)<<uint(n)
+	}
+	return int64(v)
+}
+
+func (b *bitReader) align() {
+	b.pos = (b.pos + 7) / 8 * 8
+}
+
+func crc(data []byte, poly uint16, width uint) uint16 {
+	top := uint16(1) << (width - 1)
+	mask := uint16(1<<width - 1)
+	c := uint16(0)
+	for _, d := range data {
+		c ^= uint16(d) << (width - 8)
+		for i := 0; i < 8; i++ {
+			if c&top != 0 {
+				c = (c<<1 ^ poly) & mask
+			} else {
+				c = c << 1 & mask
+			}
+		}
+	}
+	return c
+}
+
+// decodeFLAC is a minimal decoder for the subset of FLAC that FLACWriter
+// produces, which checks the structure and checksums as it goes.
+func decodeFLAC(data []byte) (*flacStream, error) {
+	if string(data[:4]) != "fLaC" || data[4] != 0x80 {
+		return nil, fmt.Errorf("missing marker or STREAMINFO")
+	}
+	r := &bitReader{data: data, pos: 8 * 8}
+	minBlock, maxBlock := r.read(16), r.read(16)
+	r.read(48)
+	s := &flacStream{sampleRate: uint32(r.read(20)), channels: int(r.read(3)) + 1}
+	bits := r.read(5) + 1
+	s.totalSamples = r.read(36)
+	s.md5 = data[26:42]
+	if minBlock != 4096 || maxBlock != 4096 || bits != 16 {
+		return nil, fmt.Errorf("unexpected block size %d-%d or bit depth %d", minBlock, maxBlock, bits)
+	}
+	s.samples = make([][]int32, s.channels)
+
+	pos := 42
+	for frame := uint64(0); pos < len(data); frame++ {
+		r := &bitReader{data: data, pos: pos * 8}
+		if r.read(16) != 0xFFF8 {
+			return nil, fmt.Errorf("frame %d: bad sync code", frame)
+		}
+		blockCode := r.read(4)
+		r.read(4)
+		if int(r.read(4))+1 != s.channels {
+			return nil, fmt.Errorf("frame %d: wrong channels", frame)
+		}
+		r.read(4)
+		// frame numbers used here fit in 1 or 2 UTF-8 bytes
+		number := r.read(8)
+		if number >= 0xC0 {
+			number = (number&0x1F)<<6 | r.read(8)&0x3F
+		}
+		if number != frame {
+			return nil, fmt.Errorf("frame %d: numbered %d", frame, number)
+		}
+		blockSize := 4096
+		if blockCode == 7 {
+			blockSize = int(r.read(16)) + 1
+		}
+		if uint16(r.read(8)) != crc(data[pos:r.pos/8-1], 0x07, 8) {
+			return nil, fmt.Errorf("frame %d: bad header CRC", frame)
+		}
+
+		for c := 0; c < s.channels; c++ {
+			r.read(1)
+			kind := r.read(6)
+			r.read(1)
+			samples := make([]int32, 0, blockSize)
+			switch {
+			case kind == 0:
+				v := int32(r.readSigned(16))
+				for i := 0; i < blockSize; i++ {
+					samples = append(samples, v)
+				}
+			case kind == 1:
+				for i := 0; i < blockSize; i++ {
+					samples = append(samples, int32(r.readSigned(16)))
+				}
+			case kind >= 8 && kind <= 12:
+				order := int(kind - 8)
+				for i := 0; i < order; i++ {
+					samples = append(samples, int32(r.readSigned(16)))
+				}
+				if r.read(2) != 0 || r.read(4) != 0 {
+					return nil, fmt.Errorf("frame %d: unexpected residual coding", frame)
+				}
+				param := int(r.read(4))
+				coeffs := [][]int64{{}, {1}, {2, -1}, {3, -3, 1}, {4, -6, 4, -1}}[order]
+				for i := order; i < blockSize; i++ {
+					q := uint64(0)
+					for r.read(1) == 0 {
+						q++
+					}
+					u := q<<uint(param) | r.read(param)
+					residual := int64(u>>1) ^ -int64(u&1)
+					prediction := int64(0)
+					for j, coeff := range coeffs {
+						prediction += coeff * int64(samples[i-1-j])
+					}
+					samples = append(samples, int32(prediction+residual))
+				}
+			default:
+				return nil, fmt.Errorf("frame %d: unexpected subframe type %d", frame, kind)
+			}
+			s.samples[c] = append(s.samples[c], samples...)
+		}
+
+		r.align()
+		end := r.pos / 8
+		if uint16(r.read(16)) != crc(data[pos:end], 0x8005, 16) {
+			return nil, fmt.Errorf("frame %d: bad frame CRC", frame)
+		}
+		pos = end + 2
+	}
+	return s, nil
+}
+
+// pcm converts interleaved samples to 16-bit little-endian PCM.
+func pcm(samples []int16) []byte {
+	b := make([]byte, 2*len(samples))
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return b
+}
+
+func TestFLACWriter(t *testing.T) {
+	// stereo with a tone, noise, silence (constant subframes) and full scale
+	// square waves, and a short final block
+	n := 3*4096 + 1000
+	tone := testutils.Sine(440, 0.5, 16000, n)
+	noise := testutils.Noise(0.3, n, 1)
+	samples := make([]int16, 0, 2*n)
+	for i := 0; i < n; i++ {
+		left := int16(tone[i] * 32767)
+		right := int16(noise[i] * 32767)
+		if i >= 4096 && i < 8192 {
+			right = 0
+		}
+		if i >= 8192 && i < 12288 && i%2 == 0 {
+			left, right = 32767, -32768
+		}
+		samples = append(samples, left, right)
+	}
+	data := pcm(samples)
+
+	out := &memFile{}
+	w, err := audio.NewFLACWriter(out, 16000, 2, 16)
+	require.Nil(t, err)
+	// write in uneven pieces
+	for i := 0; i < len(data); i += 4 * 999 {
+		end := i + 4*999
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err := w.Write(data[i:end])
+		require.Nil(t, err)
+	}
+
+	// a flushed file covers the complete blocks
+	require.Nil(t, w.Flush())
+	partial, err := decodeFLAC(append([]byte(nil), out.data...))
+	require.Nil(t, err)
+	require.Equal(t, uint64(3*4096), partial.totalSamples)
+	require.Equal(t, make([]byte, 16), partial.md5)
+	require.Len(t, partial.samples[0], 3*4096)
+
+	require.Nil(t, w.Close())
+	require.Equal(t, int64(len(out.data)), w.Size())
+	require.True(t, len(out.data) < len(data))
+
+	stream, err := decodeFLAC(out.data)
+	require.Nil(t, err)
+	require.Equal(t, uint32(16000), stream.sampleRate)
+	require.Equal(t, 2, stream.channels)
+	require.Equal(t, uint64(n), stream.totalSamples)
+	sum := md5.Sum(data)
+	require.Equal(t, sum[:], stream.md5)
+
+	decoded := make([]int16, 0, 2*n)
+	for i := range stream.samples[0] {
+		decoded = append(decoded, int16(stream.samples[0][i]), int16(stream.samples[1][i]))
+	}
+	require.True(t, bytes.Equal(data, pcm(decoded)))
+}
+
+func TestFLACWriterFormat(t *testing.T) {
+	_, err := audio.NewFLACWriter(&memFile{}, 16000, 1, 24)
+	require.NotNil(t, err)
+	require.Equal(t, errors.AudioFileInvalidFormat, err.(*errors.Error).Code)
+
+	w, err := audio.NewFLACWriter(&memFile{}, 16000, 2, 16)
+	require.Nil(t, err)
+	_, err = w.Write([]byte{1, 2, 3})
+	require.NotNil(t, err)
+}
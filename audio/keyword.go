@@ -0,0 +1,193 @@
+package audio
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultKeywordThreshold is the default highest (length-normalised) DTW
+	// distance between an utterance and an enrolled template that counts as
+	// the wake phrase. Lower values reject more false alarms, but also miss
+	// more genuine wake phrases.
+	DefaultKeywordThreshold = 5.0
+
+	// keywordSampleRate is the sample rate that audio is converted to before
+	// features are extracted, so that templates and utterances recorded at
+	// different rates can be compared.
+	keywordSampleRate = 16000
+)
+
+// KeywordSpotter detects a wake phrase (such as "hey aurora") in an
+// utterance.
+type KeywordSpotter interface {
+	// Spot looks for the wake phrase in the audio. If it is found, it
+	// returns true and the position (in seconds) at which the phrase ends,
+	// so that the speech after it can be used on its own.
+	Spot(f *File) (bool, float64)
+}
+
+// TemplateSpotter is a simple keyword spotter that compares utterances to
+// recordings of the wake phrase enrolled by the user. Audio is converted to
+// MFCC features, and each enrolled template is aligned to the best matching
+// part of the utterance with dynamic time warping (DTW), which copes with
+// the phrase being spoken faster or slower. It works best with a few
+// recordings of the phrase from each user, in the conditions it will be
+// used in.
+//
+// It is safe to enroll templates while spotting in another goroutine.
+type TemplateSpotter struct {
+	// Threshold is the highest average distance between the MFCCs of a
+	// template and the aligned part of an utterance that counts as a match
+	// (see `DefaultKeywordThreshold`).
+	Threshold float64
+
+	mu        sync.RWMutex
+	templates [][][]float64
+}
+
+// NewTemplateSpotter creates a template spotter with the given threshold and
+// no enrolled templates.
+func NewTemplateSpotter(threshold float64) *TemplateSpotter {
+	return &TemplateSpotter{Threshold: threshold}
+}
+
+// Enroll adds recordings of the wake phrase as templates. Silence around the
+// phrase is trimmed. Recordings without any speech are ignored.
+func (t *TemplateSpotter) Enroll(recordings ...*File) {
+	for _, r := range recordings {
+		segments := r.Segment(&SegmentParams{
+			MinSilence: DefaultSegmentMinSilence,
+			Threshold:  DefaultSilenceThreshold,
+		})
+		if len(segments) == 0 {
+			continue
+		}
+
+		phrase, err := r.Slice(segments[0].Start, segments[len(segments)-1].End)
+		if err != nil {
+			continue
+		}
+		features := keywordFeatures(phrase)
+		if len(features) == 0 {
+			continue
+		}
+
+		t.mu.Lock()
+		t.templates = append(t.templates, features)
+		t.mu.Unlock()
+	}
+}
+
+// Templates returns the number of enrolled templates.
+func (t *TemplateSpotter) Templates() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.templates)
+}
+
+// Spot looks for any of the enrolled templates in the audio. It never
+// matches if nothing has been enrolled.
+func (t *TemplateSpotter) Spot(f *File) (bool, float64) {
+	found, end, _ := t.match(f)
+	return found, end
+}
+
+// Score returns the distance between the audio and the closest enrolled
+// template, which can be used to choose a threshold. It returns positive
+// infinity if nothing has been enrolled.
+func (t *TemplateSpotter) Score(f *File) float64 {
+	_, _, score := t.match(f)
+	return score
+}
+
+// match aligns each template to the audio, returning whether the best one is
+// within the threshold, where it ends (in seconds) and its distance.
+func (t *TemplateSpotter) match(f *File) (bool, float64, float64) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	features := keywordFeatures(f)
+	best, bestEnd := math.Inf(1), 0
+	for _, template := range t.templates {
+		if score, end := subsequenceDTW(template, features); score < best {
+			best, bestEnd = score, end
+		}
+	}
+
+	end := math.Min(f.Duration(), float64(bestEnd)*DefaultFeatureHop+DefaultFeatureWindow)
+	return best <= t.Threshold, end, best
+}
+
+// keywordFeatures converts the audio to mono at a common sample rate and
+// returns its MFCCs, without the 0th coefficient so that the comparison
+// doesn't depend on how loud the speech is.
+func keywordFeatures(f *File) [][]float64 {
+	samples := resampleBandlimited(mixDown(f.AudioData.Samples()), f.AudioData.SampleRate, keywordSampleRate)
+	frames := NewFeatureExtractor(keywordSampleRate, nil).Push(samples)
+	features := make([][]float64, len(frames))
+	for i, frame := range frames {
+		features[i] = frame.MFCC[1:]
+	}
+	return features
+}
+
+// subsequenceDTW finds the part of `x` that `template` aligns to most
+// closely, allowing the alignment to start and end anywhere in `x`. It
+// returns the average Euclidean distance between aligned frames along the
+// best path, and the index of the frame of `x` where it ends. The distance is
+// positive infinity if either sequence is empty.
+func subsequenceDTW(template [][]float64, x [][]float64) (float64, int) {
+	if len(template) == 0 || len(x) == 0 {
+		return math.Inf(1), 0
+	}
+
+	// cost and length of the best path to each cell, one row of the template
+	// at a time
+	prevCost := make([]float64, len(x))
+	prevLen := make([]int, len(x))
+	cost := make([]float64, len(x))
+	length := make([]int, len(x))
+	for i, frame := range template {
+		for j := range x {
+			d := euclidean(frame, x[j])
+			if i == 0 {
+				// the path may start anywhere in x
+				cost[j], length[j] = d, 1
+				continue
+			}
+
+			// diagonal, vertical (repeat x[j]) and horizontal (repeat the
+			// template frame) steps
+			c, l := prevCost[j], prevLen[j]
+			if j > 0 && prevCost[j-1] <= c {
+				c, l = prevCost[j-1], prevLen[j-1]
+			}
+			if j > 0 && cost[j-1] < c {
+				c, l = cost[j-1], length[j-1]
+			}
+			cost[j], length[j] = c+d, l+1
+		}
+		prevCost, cost = cost, prevCost
+		prevLen, length = length, prevLen
+	}
+
+	best, end := math.Inf(1), 0
+	for j := range x {
+		if avg := prevCost[j] / float64(prevLen[j]); avg < best {
+			best, end = avg, j
+		}
+	}
+	return best, end
+}
+
+// euclidean returns the Euclidean distance between two vectors of the same
+// length.
+func euclidean(a []float64, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,73 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// phrase synthesises a "spoken phrase" as a sequence of harmonic tones (one
+// per syllable) at the given pitches, each `syllable` seconds long, with a
+// little background noise so that MFCCs of the silence are well defined.
+func phrase(rate uint32, syllable float64, seed int64, pitches ...float64) []float64 {
+	out := make([]float64, 0)
+	for _, p := range pitches {
+		n := int(syllable * float64(rate))
+		s := make([]float64, n)
+		for h := 1.0; h <= 4; h++ {
+			for i, v := range testutils.Sine(p*h, 0.3/h, rate, n) {
+				s[i] += v
+			}
+		}
+		out = append(out, s...)
+	}
+	for i, v := range testutils.Noise(0.002, len(out), seed) {
+		out[i] += v
+	}
+	return out
+}
+
+// withSilence surrounds the samples with `before` and `after` seconds of
+// silence.
+func withSilence(rate uint32, before float64, s []float64, after float64) []float64 {
+	out := make([]float64, int(before*float64(rate)))
+	out = append(out, s...)
+	return append(out, make([]float64, int(after*float64(rate)))...)
+}
+
+func TestTemplateSpotter(t *testing.T) {
+	wake := []float64{200, 350, 250}
+	spotter := audio.NewTemplateSpotter(audio.DefaultKeywordThreshold)
+
+	// nothing matches until a template is enrolled
+	utterance := testutils.NewFile(16000, withSilence(16000, 0.3, phrase(16000, 0.2, 3, wake...), 0.3))
+	found, _ := spotter.Spot(utterance)
+	require.False(t, found)
+
+	spotter.Enroll(
+		testutils.NewFile(16000, withSilence(16000, 0.5, phrase(16000, 0.2, 1, wake...), 0.5)),
+		testutils.NewFile(16000, withSilence(16000, 0.2, phrase(16000, 0.25, 2, wake...), 0.2)),
+		testutils.NewFile(16000, make([]float64, 16000)),
+	)
+	require.Equal(t, 2, spotter.Templates())
+
+	// the phrase followed by a command, spoken a little faster and at a
+	// different level
+	command := []float64{450, 180, 300, 400}
+	samples := withSilence(16000, 0.3, append(phrase(16000, 0.18, 3, wake...), phrase(16000, 0.2, 4, command...)...), 0.3)
+	utterance = testutils.NewFile(16000, samples)
+	utterance.ApplyGain(-10)
+	found, end := spotter.Spot(utterance)
+	require.True(t, found)
+	require.InDelta(t, 0.3+3*0.18, end, 0.05)
+
+	// a different phrase doesn't match
+	other := testutils.NewFile(16000, withSilence(16000, 0.3, phrase(16000, 0.2, 5, 500, 150, 400), 0.3))
+	found, _ = spotter.Spot(other)
+	require.False(t, found)
+	found, _ = spotter.Spot(testutils.NewFile(16000, phrase(16000, 0.2, 6, command...)))
+	require.False(t, found)
+	require.True(t, spotter.Score(other) > 2*spotter.Score(utterance))
+}
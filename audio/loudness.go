@@ -0,0 +1,164 @@
+package audio
+
+import (
+	"math"
+)
+
+const (
+	// DefaultLoudnessTarget is the integrated loudness (in LUFS) recommended
+	// by EBU R128 for broadcast audio.
+	DefaultLoudnessTarget = -23.0
+
+	// loudnessBlockLen and loudnessBlockStep are the length and step (in
+	// seconds) of the gating blocks used to measure integrated loudness,
+	// as defined by ITU-R BS.1770.
+	loudnessBlockLen  = 0.4
+	loudnessBlockStep = 0.1
+	// loudnessAbsoluteGate is the absolute gating threshold in LUFS.
+	loudnessAbsoluteGate = -70.0
+	// loudnessRelativeGate is the relative gating threshold in LU.
+	loudnessRelativeGate = -10.0
+)
+
+// ApplyGain amplifies (or attenuates, if negative) the audio data in place by
+// the given amount in decibels. Samples that would exceed full scale are
+// clipped.
+func (f *File) ApplyGain(db float64) {
+	gain := dbToLinear(db)
+	channels := f.AudioData.Samples()
+	for _, ch := range channels {
+		for i := range ch {
+			ch[i] *= gain
+		}
+	}
+	f.AudioData.SetSamples(channels)
+}
+
+// NormalizePeak scales the audio data in place so that its highest peak is at
+// the given level in dBFS (for example, -1 leaves 1dB of headroom). Silent
+// audio is left unchanged.
+func (f *File) NormalizePeak(dbfs float64) {
+	peak := f.Peak()
+	if math.IsInf(peak, -1) {
+		return
+	}
+	f.ApplyGain(dbfs - peak)
+}
+
+// NormalizeRMS scales the audio data in place so that its RMS level (across
+// all channels) is at the given level in dBFS. Silent audio is left unchanged.
+// Note that a high target may cause peaks to be clipped.
+func (f *File) NormalizeRMS(dbfs float64) {
+	level := f.RMS()
+	if math.IsInf(level, -1) {
+		return
+	}
+	f.ApplyGain(dbfs - level)
+}
+
+// Loudness measures the integrated loudness of the audio data in LUFS, as
+// specified by ITU-R BS.1770 and EBU R128 (K-weighting, 400ms blocks with 75%
+// overlap, absolute and relative gating). It returns negative infinity if the
+// audio is silent.
+func (f *File) Loudness() float64 {
+	rate := float64(f.AudioData.SampleRate)
+	channels := f.AudioData.Samples()
+	if len(channels) == 0 || len(channels[0]) == 0 {
+		return math.Inf(-1)
+	}
+
+	// K-weight each channel
+	for _, ch := range channels {
+		kWeighting(f.AudioData.SampleRate).Process(ch)
+	}
+
+	// mean square of each gating block, summed across channels
+	blockLen := int(loudnessBlockLen * rate)
+	step := int(loudnessBlockStep * rate)
+	numSamples := len(channels[0])
+	if blockLen > numSamples {
+		blockLen = numSamples
+	}
+	blocks := make([]float64, 0)
+	for start := 0; start+blockLen <= numSamples; start += step {
+		power := 0.0
+		for _, ch := range channels {
+			for _, v := range ch[start : start+blockLen] {
+				power += v * v
+			}
+		}
+		blocks = append(blocks, power/float64(blockLen))
+	}
+
+	// absolute gate, then relative gate
+	gated := gateBlocks(blocks, loudnessAbsoluteGate)
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+	gated = gateBlocks(gated, blockLoudness(mean(gated))+loudnessRelativeGate)
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+	return blockLoudness(mean(gated))
+}
+
+// NormalizeLoudness scales the audio data in place so that its integrated
+// loudness is at the given level in LUFS (see `DefaultLoudnessTarget`).
+// Silent audio is left unchanged. Note that a high target may cause peaks to
+// be clipped.
+func (f *File) NormalizeLoudness(lufs float64) {
+	current := f.Loudness()
+	if math.IsInf(current, -1) {
+		return
+	}
+	f.ApplyGain(lufs - current)
+}
+
+// blockLoudness converts the mean square of a block to its loudness in LUFS.
+func blockLoudness(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+// gateBlocks returns the blocks whose loudness is above the threshold (LUFS).
+func gateBlocks(blocks []float64, threshold float64) []float64 {
+	gated := make([]float64, 0, len(blocks))
+	for _, b := range blocks {
+		if b > 0 && blockLoudness(b) > threshold {
+			gated = append(gated, b)
+		}
+	}
+	return gated
+}
+
+// mean returns the arithmetic mean of the values.
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// kWeighting returns the two filter stages that make up the K-weighting
+// curve of ITU-R BS.1770 (a +4dB high shelf at 1500Hz modelling the acoustic
+// effect of the head, followed by a high-pass filter at 38Hz), designed for
+// the given sample rate.
+func kWeighting(sampleRate uint32) *Chain {
+	return NewChain(
+		NewHighShelf(sampleRate, 1500, 1/math.Sqrt2, 4),
+		NewHighPass(sampleRate, 38, 0.5),
+	)
+}
+
+// dbToLinear converts decibels to a linear amplitude ratio.
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// linearToDB converts a linear amplitude ratio to decibels.
+func linearToDB(v float64) float64 {
+	return 20 * math.Log10(v)
+}
@@ -0,0 +1,63 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// peakOf returns the highest absolute sample value.
+func peakOf(s []float64) float64 {
+	peak := 0.0
+	for _, v := range s {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return peak
+}
+
+// rmsOf returns the root-mean-square of the samples.
+func rmsOf(s []float64) float64 {
+	return math.Sqrt(testutils.Energy(s) / float64(len(s)))
+}
+
+// A full scale 997Hz sine in one channel measures -3.01 LUFS according to
+// BS.1770, so one at -20dBFS should measure -23.01 LUFS.
+func TestLoudnessReferenceTone(t *testing.T) {
+	f := testutils.NewFile(48000, testutils.Sine(997, 0.1, 48000, 5*48000))
+	require.InDelta(t, -23.01, f.Loudness(), 0.1)
+
+	// the filters are designed per sample rate, so 16KHz should be close too
+	f = testutils.NewFile(16000, testutils.Sine(997, 0.1, 16000, 5*16000))
+	require.InDelta(t, -23.01, f.Loudness(), 0.3)
+}
+
+// Silence has no loudness.
+func TestLoudnessSilence(t *testing.T) {
+	f := testutils.NewFile(16000, make([]float64, 16000))
+	require.True(t, math.IsInf(f.Loudness(), -1))
+
+	// and normalizing it does nothing
+	f.NormalizeLoudness(audio.DefaultLoudnessTarget)
+	require.Equal(t, 0.0, peakOf(f.AudioData.Samples()[0]))
+}
+
+func TestNormalizeLoudness(t *testing.T) {
+	f := testutils.NewFile(16000, testutils.Sine(440, 0.01, 16000, 3*16000))
+	f.NormalizeLoudness(audio.DefaultLoudnessTarget)
+	require.InDelta(t, audio.DefaultLoudnessTarget, f.Loudness(), 0.1)
+}
+
+func TestNormalizePeak(t *testing.T) {
+	f := testutils.NewFile(16000, testutils.Noise(0.2, 16000, 5))
+	f.NormalizePeak(-1)
+	require.InDelta(t, math.Pow(10, -1.0/20), peakOf(f.AudioData.Samples()[0]), 1e-3)
+}
+
+func TestNormalizeRMS(t *testing.T) {
+	f := testutils.NewFile(16000, testutils.Sine(440, 0.5, 16000, 16000))
+	f.NormalizeRMS(-30)
+	require.InDelta(t, -30, 20*math.Log10(rmsOf(f.AudioData.Samples()[0])), 0.05)
+}
@@ -0,0 +1,317 @@
+package audio
+
+import (
+	"math"
+)
+
+const (
+	// DefaultFeatureWindow and DefaultFeatureHop are the default length and
+	// step (in seconds) of the frames that features are computed for.
+	DefaultFeatureWindow = 0.025
+	DefaultFeatureHop    = 0.01
+	// DefaultFeatureFilters is the default number of triangular filters in
+	// the mel filterbank.
+	DefaultFeatureFilters = 26
+	// DefaultFeatureCoefficients is the default number of cepstral
+	// coefficients computed for each frame (including the 0th, which
+	// represents the energy of the frame).
+	DefaultFeatureCoefficients = 13
+	// DefaultDeltaWidth is the default number of frames on either side used
+	// to compute deltas.
+	DefaultDeltaWidth = 2
+
+	// featureFloor is the smallest filterbank energy, so that silence
+	// doesn't produce infinite logarithms.
+	featureFloor = 1e-10
+)
+
+// FeatureParams configures the extraction of speech features (see
+// `File.MFCC`).
+type FeatureParams struct {
+	// Window and Hop are the length and step (in seconds) of each frame.
+	Window float64
+	Hop    float64
+	// Filters is the number of triangular filters in the mel filterbank.
+	Filters int
+	// LowFreq and HighFreq are the edges (in Hz) of the filterbank. A
+	// HighFreq of 0 means the Nyquist frequency.
+	LowFreq  float64
+	HighFreq float64
+	// Coefficients is the number of cepstral coefficients to keep.
+	Coefficients int
+	// PreEmphasis is the pre-emphasis coefficient applied before analysis
+	// (0 to disable).
+	PreEmphasis float64
+	// Deltas is the number of orders of deltas appended to each frame of
+	// MFCCs by `File.MFCC`: 0 for none, 1 for deltas and 2 for deltas and
+	// delta-deltas (accelerations).
+	Deltas int
+}
+
+// NewFeatureParams creates the default set of FeatureParams, which are
+// standard for speech recognition. You should call this function to get the
+// default and then replace the ones you want to customize.
+func NewFeatureParams() *FeatureParams {
+	return &FeatureParams{
+		Window:       DefaultFeatureWindow,
+		Hop:          DefaultFeatureHop,
+		Filters:      DefaultFeatureFilters,
+		Coefficients: DefaultFeatureCoefficients,
+		PreEmphasis:  DefaultPreEmphasis,
+	}
+}
+
+// Features are the features of a single frame of audio.
+type Features struct {
+	// Filterbank is the energy in each filter of the mel filterbank.
+	Filterbank []float64
+	// LogMel is the natural logarithm of the filterbank energies.
+	LogMel []float64
+	// MFCC are the mel-frequency cepstral coefficients, i.e. the discrete
+	// cosine transform of the log-mel energies.
+	MFCC []float64
+}
+
+// FeatureExtractor computes features (mel filterbank energies, log-mel
+// energies and MFCCs) from a stream of audio. Each frame is pre-emphasised
+// and Hamming windowed before its power spectrum is passed through the
+// filterbank.
+type FeatureExtractor struct {
+	params   *FeatureParams
+	frameLen int
+	hop      int
+	fftLen   int
+	window   []float64
+	filters  [][]float64
+
+	pending []float64
+	prev    float64
+	buf     []complex128
+}
+
+// NewFeatureExtractor creates a feature extractor for audio with the given
+// sample rate. Pass `nil` to use the default parameters.
+func NewFeatureExtractor(sampleRate uint32, params *FeatureParams) *FeatureExtractor {
+	if params == nil {
+		params = NewFeatureParams()
+	}
+	if sampleRate == 0 {
+		sampleRate = DefaultSampleRate
+	}
+
+	rate := float64(sampleRate)
+	frameLen := int(math.Max(2, math.Round(params.Window*rate)))
+	fftLen := nextPow2(frameLen)
+	highFreq := params.HighFreq
+	if highFreq <= 0 || highFreq > rate/2 {
+		highFreq = rate / 2
+	}
+
+	window := make([]float64, frameLen)
+	for i := range window {
+		window[i] = 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(frameLen-1))
+	}
+
+	return &FeatureExtractor{
+		params:   params,
+		frameLen: frameLen,
+		hop:      int(math.Max(1, math.Round(params.Hop*rate))),
+		fftLen:   fftLen,
+		window:   window,
+		filters:  melFilterbank(params.Filters, fftLen, rate, params.LowFreq, highFreq),
+		pending:  make([]float64, 0, frameLen),
+		buf:      make([]complex128, fftLen),
+	}
+}
+
+// Push adds samples to the stream and returns the features of every frame
+// that was completed by them (possibly none). Deltas need frames from either
+// side, so they aren't computed here: use `Deltas` on the collected MFCCs.
+func (e *FeatureExtractor) Push(samples []float64) []*Features {
+	for _, x := range samples {
+		e.pending = append(e.pending, x-e.params.PreEmphasis*e.prev)
+		e.prev = x
+	}
+
+	features := make([]*Features, 0)
+	for len(e.pending) >= e.frameLen {
+		features = append(features, e.frame(e.pending[:e.frameLen]))
+		if e.hop >= len(e.pending) {
+			e.pending = e.pending[:0]
+			break
+		}
+		e.pending = append(e.pending[:0], e.pending[e.hop:]...)
+	}
+	return features
+}
+
+// Reset discards any buffered samples so that the extractor can be used for
+// a new stream.
+func (e *FeatureExtractor) Reset() {
+	e.pending = e.pending[:0]
+	e.prev = 0
+}
+
+// frame computes the features of a single (pre-emphasised) frame.
+func (e *FeatureExtractor) frame(frame []float64) *Features {
+	for i := range e.buf {
+		e.buf[i] = 0
+	}
+	for i, v := range frame {
+		e.buf[i] = complex(v*e.window[i], 0)
+	}
+	fft(e.buf)
+
+	f := &Features{
+		Filterbank: make([]float64, len(e.filters)),
+		LogMel:     make([]float64, len(e.filters)),
+	}
+	for m, filter := range e.filters {
+		energy := 0.0
+		for k, w := range filter {
+			if w != 0 {
+				re, im := real(e.buf[k]), imag(e.buf[k])
+				energy += w * (re*re + im*im)
+			}
+		}
+		f.Filterbank[m] = energy
+		f.LogMel[m] = math.Log(math.Max(energy, featureFloor))
+	}
+	f.MFCC = dct(f.LogMel, e.params.Coefficients)
+	return f
+}
+
+// Filterbank computes the mel filterbank energies of each frame of the audio
+// (mixed down to mono). Pass `nil` to use the default parameters.
+func (f *File) Filterbank(params *FeatureParams) [][]float64 {
+	frames := f.features(params)
+	out := make([][]float64, len(frames))
+	for i, frame := range frames {
+		out[i] = frame.Filterbank
+	}
+	return out
+}
+
+// LogMel computes the log-mel energies of each frame of the audio (mixed down
+// to mono). Pass `nil` to use the default parameters.
+func (f *File) LogMel(params *FeatureParams) [][]float64 {
+	frames := f.features(params)
+	out := make([][]float64, len(frames))
+	for i, frame := range frames {
+		out[i] = frame.LogMel
+	}
+	return out
+}
+
+// MFCC computes the mel-frequency cepstral coefficients of each frame of the
+// audio (mixed down to mono), followed by their deltas if requested by
+// `params.Deltas`. Pass `nil` to use the default parameters.
+func (f *File) MFCC(params *FeatureParams) [][]float64 {
+	if params == nil {
+		params = NewFeatureParams()
+	}
+	frames := f.features(params)
+	out := make([][]float64, len(frames))
+	for i, frame := range frames {
+		out[i] = frame.MFCC
+	}
+
+	order := out
+	for d := 0; d < params.Deltas; d++ {
+		order = Deltas(order, DefaultDeltaWidth)
+		for i := range out {
+			out[i] = append(out[i], order[i]...)
+		}
+	}
+	return out
+}
+
+// Deltas computes the deltas (time derivatives) of a sequence of feature
+// vectors using linear regression over `width` frames on either side, with
+// the first and last frames repeated at the edges. Applying it to its own
+// output gives delta-deltas.
+func Deltas(features [][]float64, width int) [][]float64 {
+	if width < 1 {
+		width = DefaultDeltaWidth
+	}
+	denom := 0.0
+	for n := 1; n <= width; n++ {
+		denom += 2 * float64(n*n)
+	}
+
+	clamp := func(i int) int {
+		return int(math.Max(0, math.Min(float64(len(features)-1), float64(i))))
+	}
+	out := make([][]float64, len(features))
+	for t := range features {
+		out[t] = make([]float64, len(features[t]))
+		for n := 1; n <= width; n++ {
+			next, prev := features[clamp(t+n)], features[clamp(t-n)]
+			for k := range out[t] {
+				out[t][k] += float64(n) * (next[k] - prev[k]) / denom
+			}
+		}
+	}
+	return out
+}
+
+// features runs a feature extractor over the whole audio.
+func (f *File) features(params *FeatureParams) []*Features {
+	return NewFeatureExtractor(f.AudioData.SampleRate, params).Push(mixDown(f.AudioData.Samples()))
+}
+
+// melFilterbank creates `n` triangular filters, equally spaced on the mel
+// scale between `low` and `high` Hz. Each filter holds a weight for each of
+// the `fftLen/2 + 1` non-negative frequency bins.
+func melFilterbank(n int, fftLen int, sampleRate float64, low float64, high float64) [][]float64 {
+	minMel, maxMel := hzToMel(low), hzToMel(high)
+	edges := make([]float64, n+2)
+	for i := range edges {
+		edges[i] = melToHz(minMel+(maxMel-minMel)*float64(i)/float64(n+1)) * float64(fftLen) / sampleRate
+	}
+
+	filters := make([][]float64, n)
+	for m := range filters {
+		filters[m] = make([]float64, fftLen/2+1)
+		lo, centre, hi := edges[m], edges[m+1], edges[m+2]
+		for k := range filters[m] {
+			bin := float64(k)
+			switch {
+			case bin > lo && bin <= centre:
+				filters[m][k] = (bin - lo) / (centre - lo)
+			case bin > centre && bin < hi:
+				filters[m][k] = (hi - bin) / (hi - centre)
+			}
+		}
+	}
+	return filters
+}
+
+// dct computes the first `n` coefficients of the orthonormal type-II
+// discrete cosine transform of `x`.
+func dct(x []float64, n int) []float64 {
+	out := make([]float64, n)
+	size := float64(len(x))
+	for k := range out {
+		sum := 0.0
+		for i, v := range x {
+			sum += v * math.Cos(math.Pi*float64(k)*(float64(i)+0.5)/size)
+		}
+		scale := math.Sqrt(2 / size)
+		if k == 0 {
+			scale = math.Sqrt(1 / size)
+		}
+		out[k] = sum * scale
+	}
+	return out
+}
+
+// hzToMel converts a frequency in Hz to the mel scale.
+func hzToMel(hz float64) float64 {
+	return 2595 * math.Log10(1+hz/700)
+}
+
+// melToHz converts a frequency on the mel scale to Hz.
+func melToHz(mel float64) float64 {
+	return 700 * (math.Pow(10, mel/2595) - 1)
+}
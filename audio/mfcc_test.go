@@ -0,0 +1,113 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// Reference features of a 1KHz sine at half scale, sampled at 16KHz, with the
+// default parameters. These were computed with an independent (naive DFT)
+// implementation of the same pipeline.
+var (
+	referenceLogMel = map[int][]float64{
+		0: {-6.9856, -7.1382, -6.4018, -6.1569, -5.6583, -4.9376},
+		3: {-6.1732, -6.699, -5.9124, -5.7081, -5.3954, -4.7534},
+	}
+	referenceMFCC = map[int][]float64{
+		0: {-22.4127, 3.1951, -7.587, -8.3428, -2.4922, 3.7123, 5.0708, 1.0827, -3.4473, -4.0485, -0.5028, 3.1182, 3.2124},
+		3: {-22.1656, 4.1697, -6.9909, -8.0107, -2.3131, 3.8074, 5.1296, 1.1196, -3.4119, -4.0183, -0.4588, 3.1676, 3.2694},
+	}
+)
+
+func TestFeatureExtractorReference(t *testing.T) {
+	samples := testutils.Sine(1000, 0.5, 16000, 1600)
+	frames := audio.NewFeatureExtractor(16000, nil).Push(samples)
+	require.Len(t, frames, 8)
+
+	for i, expected := range referenceLogMel {
+		require.InDeltaSlice(t, expected, frames[i].LogMel[:len(expected)], 1e-3, "frame %d", i)
+		require.InDelta(t, math.Exp(frames[i].LogMel[0]), frames[i].Filterbank[0], 1e-12)
+	}
+	for i, expected := range referenceMFCC {
+		require.InDeltaSlice(t, expected, frames[i].MFCC, 1e-3, "frame %d", i)
+	}
+}
+
+// Pushing the audio in pieces gives the same features as all at once.
+func TestFeatureExtractorStreaming(t *testing.T) {
+	samples := testutils.Sine(440, 0.5, 16000, 16000)
+	whole := audio.NewFeatureExtractor(16000, nil).Push(samples)
+
+	e := audio.NewFeatureExtractor(16000, nil)
+	streamed := make([]*audio.Features, 0)
+	for i := 0; i < len(samples); i += 1000 {
+		streamed = append(streamed, e.Push(samples[i:i+1000])...)
+	}
+	require.Len(t, streamed, len(whole))
+	for i := range whole {
+		require.InDeltaSlice(t, whole[i].MFCC, streamed[i].MFCC, 1e-9)
+	}
+
+	e.Reset()
+	require.Len(t, e.Push(samples[:399]), 0)
+	require.Len(t, e.Push(samples[399:400]), 1)
+}
+
+func TestMFCCParams(t *testing.T) {
+	f := testutils.NewFile(8000, testutils.Sine(440, 0.5, 8000, 8000))
+	params := audio.NewFeatureParams()
+	params.Window = 0.05
+	params.Hop = 0.02
+	params.Filters = 40
+	params.Coefficients = 20
+	params.HighFreq = 3000
+
+	require.Len(t, f.MFCC(params), 48)
+	require.Len(t, f.MFCC(params)[0], 20)
+	require.Len(t, f.LogMel(params)[0], 40)
+	require.Len(t, f.Filterbank(params)[0], 40)
+
+	// the loudest filter is the one centred on 440Hz: the filters are 45.8
+	// mels apart up to 3KHz (1876.5 mels) and 440Hz is 549.6 mels, so that's
+	// the 12th
+	fb := f.Filterbank(params)[10]
+	loudest := 0
+	for m, e := range fb {
+		if e > fb[loudest] {
+			loudest = m
+		}
+	}
+	require.Equal(t, 11, loudest)
+
+	params.Deltas = 2
+	require.Len(t, f.MFCC(params)[0], 60)
+}
+
+// Silence only has energy at the floor, so every coefficient but the first
+// is zero.
+func TestMFCCSilence(t *testing.T) {
+	mfcc := testutils.NewFile(16000, make([]float64, 1600)).MFCC(nil)
+	require.Len(t, mfcc, 8)
+	require.InDelta(t, math.Sqrt(26)*math.Log(1e-10), mfcc[0][0], 1e-9)
+	for _, c := range mfcc[0][1:] {
+		require.InDelta(t, 0, c, 1e-9)
+	}
+}
+
+func TestDeltas(t *testing.T) {
+	// a ramp has a constant slope, except where the edges are repeated
+	features := make([][]float64, 10)
+	for i := range features {
+		features[i] = []float64{float64(i), 3 * float64(i), 5}
+	}
+	deltas := audio.Deltas(features, 2)
+	require.InDeltaSlice(t, []float64{1, 3, 0}, deltas[5], 1e-12)
+	require.InDeltaSlice(t, []float64{0.5, 1.5, 0}, deltas[0], 1e-12)
+
+	accel := audio.Deltas(deltas, 2)
+	require.InDeltaSlice(t, []float64{0, 0, 0}, accel[5], 1e-12)
+}
@@ -0,0 +1,197 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// DefaultNoiseSuppression is a moderate noise suppression strength that
+	// removes steady background noise without noticeably affecting speech.
+	DefaultNoiseSuppression = 0.5
+
+	// noiseFrameLen is the length (in seconds) of each analysis frame.
+	noiseFrameLen = 0.032
+	// noiseInitFrames is the number of frames at the start of the signal that
+	// are assumed to be noise and used to seed the noise estimate.
+	noiseInitFrames = 8
+	// noiseRise is how much the noise estimate is allowed to increase per
+	// frame, which lets it follow noise that gets louder without following
+	// speech.
+	noiseRise = 1.005
+	// noiseSmoothing is the smoothing factor applied to the power spectrum
+	// before it is compared with the noise estimate.
+	noiseSmoothing = 0.7
+	// noiseDecisionDirected is the weight given to the previous frame when
+	// estimating the a priori SNR (the "decision-directed" approach).
+	noiseDecisionDirected = 0.98
+	// noiseMaxAttenuation is the attenuation (in dB) applied to noise-only
+	// bins at full strength.
+	noiseMaxAttenuation = 25.0
+)
+
+// NoiseSuppressor removes stationary background noise (hum, fans, engines,
+// etc.) from audio. It tracks the noise spectrum over time and applies a
+// Wiener filter with spectral over-subtraction in the short-time Fourier
+// domain.
+//
+// It is a Processor, so it can be added to the recording pipeline or applied
+// to a file with `File.Apply`. It delays its output by `Delay()` samples.
+type NoiseSuppressor struct {
+	// frameLen and hop describe the STFT (with 50% overlap)
+	frameLen int
+	hop      int
+	window   []float64
+
+	// oversubtraction and floor are derived from the strength
+	oversubtraction float64
+	floor           float64
+
+	// the noise estimate, smoothed power, and the previous frame's gain and
+	// a posteriori SNR for each bin
+	noise    []float64
+	smoothed []float64
+	gain     []float64
+	post     []float64
+	frames   int
+
+	// input holds the most recent `frameLen` input samples (the last `hop`
+	// of which are filled in as they arrive), pending counts how many of
+	// those have arrived, ola accumulates the overlap-added output and
+	// output holds samples ready to be returned
+	input   []float64
+	pending int
+	ola     []float64
+	output  []float64
+}
+
+// NewNoiseSuppressor creates a noise suppressor for audio with the given
+// sample rate. `strength` ranges from 0 (no suppression) to 1 (aggressive
+// suppression, which may introduce artifacts); `DefaultNoiseSuppression` is a
+// good starting point.
+func NewNoiseSuppressor(sampleRate uint32, strength float64) *NoiseSuppressor {
+	if sampleRate == 0 {
+		sampleRate = DefaultSampleRate
+	}
+	strength = math.Max(0, math.Min(1, strength))
+
+	frameLen := nextPow2(int(noiseFrameLen * float64(sampleRate)))
+	window := hann(frameLen)
+	for i := range window {
+		window[i] = math.Sqrt(window[i])
+	}
+
+	n := &NoiseSuppressor{
+		frameLen:        frameLen,
+		hop:             frameLen / 2,
+		window:          window,
+		oversubtraction: 1 + 2*strength,
+		floor:           math.Pow(10, -strength*noiseMaxAttenuation/20),
+	}
+	n.Reset()
+	return n
+}
+
+// Delay returns the number of samples by which the output lags the input.
+func (n *NoiseSuppressor) Delay() int {
+	return n.frameLen
+}
+
+// Process suppresses the noise in a frame of samples.
+func (n *NoiseSuppressor) Process(frame []float64) []float64 {
+	for _, v := range frame {
+		n.input[n.frameLen-n.hop+n.pending] = v
+		n.pending++
+		if n.pending == n.hop {
+			n.processFrame()
+			copy(n.input, n.input[n.hop:])
+			n.pending = 0
+		}
+	}
+
+	copy(frame, n.output)
+	n.output = n.output[len(frame):]
+	return frame
+}
+
+// Reset clears the noise estimate and any buffered audio.
+func (n *NoiseSuppressor) Reset() {
+	bins := n.frameLen/2 + 1
+	n.noise = make([]float64, bins)
+	n.smoothed = make([]float64, bins)
+	n.gain = make([]float64, bins)
+	n.post = make([]float64, bins)
+	for i := range n.gain {
+		n.gain[i] = 1
+		n.post[i] = 1
+	}
+	n.frames = 0
+	n.input = make([]float64, n.frameLen)
+	n.pending = 0
+	n.ola = make([]float64, n.frameLen)
+	// start with `hop` samples of silence so that there is always enough
+	// output to return
+	n.output = make([]float64, n.hop)
+}
+
+// processFrame filters the frame currently in the input buffer and adds it to
+// the output.
+func (n *NoiseSuppressor) processFrame() {
+	spectrum := make([]complex128, n.frameLen)
+	for i, v := range n.input {
+		spectrum[i] = complex(v*n.window[i], 0)
+	}
+	fft(spectrum)
+
+	bins := n.frameLen/2 + 1
+	n.frames++
+	for k := 0; k < bins; k++ {
+		power := real(spectrum[k])*real(spectrum[k]) + imag(spectrum[k])*imag(spectrum[k])
+
+		// update the noise estimate: average the first few frames, then
+		// follow the (smoothed) minimum, rising slowly
+		if n.frames <= noiseInitFrames {
+			n.noise[k] += (power - n.noise[k]) / float64(n.frames)
+			n.smoothed[k] = n.noise[k]
+		} else {
+			n.smoothed[k] = noiseSmoothing*n.smoothed[k] + (1-noiseSmoothing)*power
+			if n.smoothed[k] < n.noise[k] {
+				n.noise[k] = n.smoothed[k]
+			} else {
+				n.noise[k] *= noiseRise
+			}
+		}
+
+		// Wiener gain from the decision-directed a priori SNR
+		noise := n.oversubtraction*n.noise[k] + 1e-12
+		post := power / noise
+		prio := noiseDecisionDirected*n.gain[k]*n.gain[k]*n.post[k] + (1-noiseDecisionDirected)*math.Max(post-1, 0)
+		g := math.Max(prio/(1+prio), n.floor)
+		n.gain[k] = g
+		n.post[k] = post
+
+		spectrum[k] *= complex(g, 0)
+		if k > 0 && k < n.frameLen/2 {
+			spectrum[n.frameLen-k] = cmplx.Conj(spectrum[k])
+		}
+	}
+	ifft(spectrum)
+
+	// overlap-add the filtered frame, then emit the first hop (which will
+	// not receive any more contributions)
+	for i := range n.ola {
+		n.ola[i] += real(spectrum[i]) * n.window[i]
+	}
+	n.output = append(n.output, n.ola[:n.hop]...)
+	copy(n.ola, n.ola[n.hop:])
+	for i := n.frameLen - n.hop; i < n.frameLen; i++ {
+		n.ola[i] = 0
+	}
+}
+
+// SuppressNoise removes stationary background noise from the audio data in
+// place. `strength` ranges from 0 (no suppression) to 1 (aggressive
+// suppression). See `NoiseSuppressor` for more details.
+func (f *File) SuppressNoise(strength float64) {
+	f.Apply(NewNoiseSuppressor(f.AudioData.SampleRate, strength))
+}
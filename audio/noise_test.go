@@ -0,0 +1,92 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// noisyTone returns one second of noise followed by two seconds of a tone
+// mixed with the same level of noise, along with the clean tone.
+func noisyTone() (noisy []float64, clean []float64) {
+	rate := audio.DefaultSampleRate
+	n := 3 * int(rate)
+	noise := testutils.Noise(0.05, n, 4)
+	tone := testutils.Sine(300, 0.3, rate, n)
+
+	noisy = make([]float64, n)
+	clean = make([]float64, n)
+	for i := range noisy {
+		if i >= int(rate) {
+			clean[i] = tone[i]
+		}
+		noisy[i] = clean[i] + noise[i]
+	}
+	return noisy, clean
+}
+
+// Noise-only sections should be strongly attenuated while the tone is kept.
+func TestSuppressNoise(t *testing.T) {
+	rate := int(audio.DefaultSampleRate)
+	noisy, clean := noisyTone()
+
+	f := testutils.NewFile(audio.DefaultSampleRate, noisy)
+	f.SuppressNoise(1)
+	out := f.AudioData.Samples()[0]
+	require.Len(t, out, len(noisy))
+
+	// noise in the first second (skipping the initial estimate) goes down
+	before := testutils.Energy(noisy[rate/2 : rate])
+	after := testutils.Energy(out[rate/2 : rate])
+	require.True(t, 10*math.Log10(before/after) > 10, "noise reduced by %.1f dB", 10*math.Log10(before/after))
+
+	// the tone is still there, and the output is closer to it than before
+	errBefore, errAfter := 0.0, 0.0
+	for i := 2 * rate; i < 3*rate-1000; i++ {
+		errBefore += (noisy[i] - clean[i]) * (noisy[i] - clean[i])
+		errAfter += (out[i] - clean[i]) * (out[i] - clean[i])
+	}
+	require.True(t, errAfter < errBefore)
+	require.InDelta(t, testutils.Energy(clean[2*rate:3*rate-1000]), testutils.Energy(out[2*rate:3*rate-1000]), 0.2*testutils.Energy(clean[2*rate:3*rate-1000]))
+}
+
+// A strength of 0 should pass the audio through untouched (apart from
+// quantization).
+func TestSuppressNoiseZeroStrength(t *testing.T) {
+	noisy, _ := noisyTone()
+
+	f := testutils.NewFile(audio.DefaultSampleRate, noisy)
+	f.SuppressNoise(0)
+	out := f.AudioData.Samples()[0]
+	for i := range noisy {
+		require.InDelta(t, noisy[i], out[i], 1e-3)
+	}
+}
+
+// Frames processed through the live pipeline are delayed by `Delay()` but
+// otherwise equal to processing the whole file.
+func TestNoiseSuppressorProcess(t *testing.T) {
+	noisy, _ := noisyTone()
+	ns := audio.NewNoiseSuppressor(audio.DefaultSampleRate, audio.DefaultNoiseSuppression)
+
+	out := make([]float64, 0, len(noisy))
+	for i := 0; i < len(noisy); i += 100 {
+		end := i + 100
+		if end > len(noisy) {
+			end = len(noisy)
+		}
+		out = append(out, ns.Process(append([]float64(nil), noisy[i:end]...))...)
+	}
+	require.Len(t, out, len(noisy))
+
+	f := testutils.NewFile(audio.DefaultSampleRate, noisy)
+	f.SuppressNoise(audio.DefaultNoiseSuppression)
+	whole := f.AudioData.Samples()[0]
+	d := ns.Delay()
+	for i := d; i < len(out); i += 97 {
+		require.InDelta(t, whole[i-d], out[i], 1e-3)
+	}
+}
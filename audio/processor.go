@@ -0,0 +1,71 @@
+package audio
+
+// Processor is a stage in an audio processing pipeline, such as an echo
+// canceller or a noise suppressor. Processors operate on a single channel of
+// audio and may keep state between calls, so the same Processor can be fed
+// consecutive frames from a live recording or the whole of a file.
+type Processor interface {
+	// Process takes a frame of samples in the range [-1, 1) and returns the
+	// processed frame. The returned frame must have the same length as the
+	// input, although the processor is allowed to introduce a delay. The
+	// input may be modified and returned in place.
+	Process(frame []float64) []float64
+	// Reset clears any internal state so that the processor can be used on
+	// an unrelated signal.
+	Reset()
+}
+
+// delayer is implemented by processors that delay their output, such as
+// those that operate on overlapping blocks of samples.
+type delayer interface {
+	// Delay returns the number of samples by which the output lags the input
+	Delay() int
+}
+
+// syncer is implemented by processors that need to know when a live
+// recording starts capturing, such as an echo canceller aligning its
+// reference signal with the microphone.
+type syncer interface {
+	// Sync is called once capture has started, before the first frame
+	Sync()
+}
+
+// Apply runs the given processors over the audio data, in order. Each
+// channel is processed independently (the processors are reset before each
+// channel), and the result replaces the audio data in place. Any delay
+// introduced by the processors is compensated for, so the result stays
+// aligned with the original.
+func (f *File) Apply(processors ...Processor) {
+	if len(processors) == 0 {
+		return
+	}
+
+	delay := NewChain(processors...).Delay()
+	channels := f.AudioData.Samples()
+	for c := range channels {
+		for _, p := range processors {
+			p.Reset()
+		}
+
+		// flush the processors with silence, then drop the delayed samples
+		in := append(channels[c], make([]float64, delay)...)
+		out := make([]float64, 0, len(in))
+		for i := 0; i < len(in); i += BufSize {
+			end := i + BufSize
+			if end > len(in) {
+				end = len(in)
+			}
+			out = append(out, process(in[i:end], processors)...)
+		}
+		channels[c] = out[delay:]
+	}
+	f.AudioData.SetSamples(channels)
+}
+
+// process passes a frame through each of the processors in turn.
+func process(frame []float64, processors []Processor) []float64 {
+	for _, p := range processors {
+		frame = p.Process(frame)
+	}
+	return frame
+}
@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/auroraapi/aurora-go/config"
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+const (
+	// The default quality requirements (see `config.NewQualityParams`).
+	DefaultQualityMinDuration      = config.DefaultQualityMinDuration
+	DefaultQualityMaxSilenceRatio  = config.DefaultQualityMaxSilenceRatio
+	DefaultQualityMaxClippingRatio = config.DefaultQualityMaxClippingRatio
+	DefaultQualityMinSNR           = config.DefaultQualityMinSNR
+	DefaultQualityMinSampleRate    = config.DefaultQualityMinSampleRate
+)
+
+// QualityParams are the requirements that audio must meet to pass a quality
+// check (see `QualityReport.Check`).
+type QualityParams = config.QualityParams
+
+// NewQualityParams creates the default set of QualityParams. You should
+// call this function to get the default and then replace the ones you want
+// to customize.
+func NewQualityParams() *QualityParams {
+	return config.NewQualityParams()
+}
+
+// QualityReport is an assessment of how suitable a piece of audio is for
+// speech recognition.
+type QualityReport struct {
+	// Duration is the length of the audio in seconds.
+	Duration float64
+	// SampleRate is the sample rate of the audio in Hz.
+	SampleRate uint32
+	// SilenceRatio is the fraction (between 0 and 1) of the audio that is
+	// silent, as judged by the recording framework's voice activity detection.
+	SilenceRatio float64
+	// ClippingRatio is the fraction (between 0 and 1) of samples that are
+	// clipped (see `ClippedSamples`).
+	ClippingRatio float64
+	// SNR is the estimated signal to noise ratio in dB (see `File.SNR`).
+	SNR float64
+}
+
+// Quality assesses how suitable the audio is for speech recognition. Use
+// `Check` on the result to find out whether it meets a set of requirements.
+func (f *File) Quality() *QualityReport {
+	rate := float64(f.AudioData.SampleRate)
+	mono := mixDown(f.AudioData.Samples())
+	frameLen := int(math.Max(1, math.Round(segmentFrameLen*rate)))
+
+	frames, silent := 0, 0
+	for i := 0; i < len(mono); i += frameLen {
+		frames++
+		if isSilentFrame(mono[i:int(math.Min(float64(i+frameLen), float64(len(mono))))], DefaultSilenceThreshold) {
+			silent++
+		}
+	}
+
+	r := &QualityReport{
+		Duration:     f.Duration(),
+		SampleRate:   f.AudioData.SampleRate,
+		SilenceRatio: 1,
+		SNR:          f.SNR(),
+	}
+	if frames > 0 {
+		r.SilenceRatio = float64(silent) / float64(frames)
+	}
+	if n := f.numFrames() * int(f.AudioData.NumChannels); n > 0 {
+		r.ClippingRatio = float64(f.ClippedSamples()) / float64(n)
+	}
+	return r
+}
+
+// Problems returns an error for each of the requirements that the audio
+// doesn't meet, in the order: silent, too short, low sample rate, clipped,
+// noisy. Silent audio is only reported as silent, since the other
+// measurements are meaningless for it, and the SNR isn't checked if it
+// couldn't be estimated (see `File.SNR`). Pass `nil` to use the default
+// requirements.
+func (r *QualityReport) Problems(params *QualityParams) []*errors.Error {
+	if params == nil {
+		params = NewQualityParams()
+	}
+
+	problems := make([]*errors.Error, 0)
+	if r.SilenceRatio > params.MaxSilenceRatio {
+		info := fmt.Sprintf("%.0f%% of the audio is silent (at most %.0f%% allowed).", 100*r.SilenceRatio, 100*params.MaxSilenceRatio)
+		return append(problems, errors.NewFromErrorCodeInfo(errors.AudioFileSilent, info))
+	}
+	if r.Duration < params.MinDuration {
+		info := fmt.Sprintf("The audio is %.2fs long (at least %.2fs required).", r.Duration, params.MinDuration)
+		problems = append(problems, errors.NewFromErrorCodeInfo(errors.AudioFileTooShort, info))
+	}
+	if r.SampleRate < params.MinSampleRate {
+		info := fmt.Sprintf("The audio is sampled at %dHz (at least %dHz required).", r.SampleRate, params.MinSampleRate)
+		problems = append(problems, errors.NewFromErrorCodeInfo(errors.AudioFileLowSampleRate, info))
+	}
+	if r.ClippingRatio > params.MaxClippingRatio {
+		info := fmt.Sprintf("%.2f%% of the samples are clipped (at most %.2f%% allowed).", 100*r.ClippingRatio, 100*params.MaxClippingRatio)
+		problems = append(problems, errors.NewFromErrorCodeInfo(errors.AudioFileClipped, info))
+	}
+	if !math.IsNaN(r.SNR) && r.SNR < params.MinSNR {
+		info := fmt.Sprintf("The estimated signal to noise ratio is %.1fdB (at least %.1fdB required).", r.SNR, params.MinSNR)
+		problems = append(problems, errors.NewFromErrorCodeInfo(errors.AudioFileNoisy, info))
+	}
+	return problems
+}
+
+// Check returns an error describing the first requirement that the audio
+// doesn't meet (see `Problems`), or nil if it is acceptable. Pass `nil` to
+// use the default requirements.
+func (r *QualityReport) Check(params *QualityParams) error {
+	if problems := r.Problems(params); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
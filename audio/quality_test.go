@@ -0,0 +1,73 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// speechLike returns a second of tone with pauses and a little background
+// noise, which should pass the default quality check.
+func speechLike(rate uint32, amp float64) []float64 {
+	s := utterances(rate, 1, [2]float64{0.1, 0.4}, [2]float64{0.6, 0.9})
+	for i, v := range testutils.Noise(0.001, len(s), 1) {
+		s[i] = s[i]*amp/0.5 + v
+	}
+	return s
+}
+
+func codesOf(problems []*errors.Error) []string {
+	codes := make([]string, len(problems))
+	for i, p := range problems {
+		codes[i] = p.Code
+	}
+	return codes
+}
+
+func TestQuality(t *testing.T) {
+	q := testutils.NewFile(16000, speechLike(16000, 0.5)).Quality()
+	require.InDelta(t, 1, q.Duration, 1e-9)
+	require.Equal(t, uint32(16000), q.SampleRate)
+	// 64ms frames that overlap a tone aren't silent
+	require.InDelta(t, 0.3, q.SilenceRatio, 0.1)
+	require.Equal(t, 0.0, q.ClippingRatio)
+	require.True(t, q.SNR > 40)
+	require.Len(t, q.Problems(nil), 0)
+	require.Nil(t, q.Check(nil))
+}
+
+func TestQualityProblems(t *testing.T) {
+	silent := testutils.NewFile(16000, make([]float64, 16000)).Quality()
+	require.Equal(t, []string{errors.AudioFileSilent}, codesOf(silent.Problems(nil)))
+	require.Equal(t, errors.AudioFileSilent, silent.Check(nil).(*errors.Error).Code)
+
+	short := testutils.NewFile(16000, testutils.Sine(300, 0.5, 16000, 1600)).Quality()
+	require.Contains(t, codesOf(short.Problems(nil)), errors.AudioFileTooShort)
+
+	// too short to estimate the SNR, which isn't reported as noise
+	tiny := testutils.NewFile(16000, testutils.Sine(300, 0.5, 16000, 100)).Quality()
+	require.Equal(t, []string{errors.AudioFileTooShort}, codesOf(tiny.Problems(nil)))
+
+	lowRate := testutils.NewFile(4000, speechLike(4000, 0.5)).Quality()
+	require.Equal(t, []string{errors.AudioFileLowSampleRate}, codesOf(lowRate.Problems(nil)))
+
+	clipped := testutils.NewFile(16000, speechLike(16000, 0.5))
+	clipped.ApplyGain(20)
+	require.Equal(t, []string{errors.AudioFileClipped}, codesOf(clipped.Quality().Problems(nil)))
+
+	noisy := speechLike(16000, 0.05)
+	for i, v := range testutils.Noise(0.05, len(noisy), 2) {
+		noisy[i] += v
+	}
+	problems := testutils.NewFile(16000, noisy).Quality().Problems(nil)
+	require.Equal(t, []string{errors.AudioFileNoisy}, codesOf(problems))
+	require.NotEmpty(t, problems[0].Info)
+
+	// the requirements can be relaxed
+	params := audio.NewQualityParams()
+	params.MinSNR = -10
+	require.Nil(t, testutils.NewFile(16000, noisy).Quality().Check(params))
+}
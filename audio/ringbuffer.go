@@ -0,0 +1,193 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+// DefaultRingBufferSize is the default number of buffers (of `BufSize`
+// samples each, about a minute of audio in total) that a recording holds
+// while waiting for them to be consumed.
+const DefaultRingBufferSize = 1000
+
+// OverflowPolicy decides what happens when audio is captured faster than it
+// is consumed and the recording's ring buffer fills up.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock pauses capture until there is room in the buffer. Audio
+	// may be lost by the device (rather than the SDK) if it blocks for long.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered audio to make room, so
+	// that the consumer always receives the most recent audio.
+	OverflowDropOldest
+	// OverflowError stops the recording with an `AudioFileBufferOverflow`
+	// error.
+	OverflowError
+)
+
+// RingBuffer is a fixed-size queue of audio buffers that connects the
+// goroutine capturing audio to its consumer. It is lock-free, and supports
+// exactly one writer and one reader at a time. When it is full, writes
+// behave according to its `OverflowPolicy`.
+type RingBuffer struct {
+	// read and write are the total number of buffers read and written. They
+	// come first so that they are aligned for atomic access on 32-bit
+	// platforms.
+	read    uint64
+	write   uint64
+	dropped uint64
+
+	slots  []atomic.Value
+	policy OverflowPolicy
+
+	closed int32
+	err    error
+	// notify wakes up a reader or writer that is waiting for the other, and
+	// done is closed along with the buffer to wake up both
+	notify   chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewRingBuffer creates a ring buffer that holds up to `size` buffers of
+// audio. If `size` is not positive, `DefaultRingBufferSize` is used.
+func NewRingBuffer(size int, policy OverflowPolicy) *RingBuffer {
+	if size <= 0 {
+		size = DefaultRingBufferSize
+	}
+	return &RingBuffer{
+		slots:  make([]atomic.Value, size),
+		policy: policy,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// Write adds a copy of the samples to the buffer. If the buffer is full, it
+// blocks, drops the oldest buffer or returns an `AudioFileBufferOverflow`
+// error, depending on the overflow policy. Writing to a closed buffer returns
+// `io.ErrClosedPipe`.
+func (r *RingBuffer) Write(samples []int16) error {
+	frame := make([]int16, len(samples))
+	copy(frame, samples)
+
+	for {
+		if r.isClosed() {
+			return io.ErrClosedPipe
+		}
+
+		w := atomic.LoadUint64(&r.write)
+		rd := atomic.LoadUint64(&r.read)
+		if w-rd < uint64(len(r.slots)) {
+			r.slots[w%uint64(len(r.slots))].Store(frame)
+			atomic.StoreUint64(&r.write, w+1)
+			r.wake()
+			return nil
+		}
+
+		switch r.policy {
+		case OverflowDropOldest:
+			// the reader may take the oldest buffer first, in which case
+			// there is room on the next attempt anyway
+			if atomic.CompareAndSwapUint64(&r.read, rd, rd+1) {
+				atomic.AddUint64(&r.dropped, 1)
+			}
+		case OverflowError:
+			return errors.NewFromErrorCodeInfo(errors.AudioFileBufferOverflow, fmt.Sprintf("%d buffers were waiting to be read.", len(r.slots)))
+		default:
+			select {
+			case <-r.notify:
+			case <-r.done:
+			}
+		}
+	}
+}
+
+// Read removes and returns the oldest buffer of samples, blocking until one
+// is available. Once the buffer is closed and empty, it returns the error it
+// was closed with, or `io.EOF`.
+func (r *RingBuffer) Read() ([]int16, error) {
+	for {
+		rd := atomic.LoadUint64(&r.read)
+		if rd < atomic.LoadUint64(&r.write) {
+			// with the drop oldest policy the writer may discard (and then
+			// overwrite) this slot while we read it, in which case the read
+			// position will have moved and we try again. Buffers are never
+			// modified once stored, so the copy we took is always intact.
+			frame := r.slots[rd%uint64(len(r.slots))].Load().([]int16)
+			if atomic.CompareAndSwapUint64(&r.read, rd, rd+1) {
+				r.wake()
+				return frame, nil
+			}
+			continue
+		}
+
+		if r.isClosed() {
+			// the writer may have written just before closing
+			if atomic.LoadUint64(&r.read) < atomic.LoadUint64(&r.write) {
+				continue
+			}
+			if r.err != nil {
+				return nil, r.err
+			}
+			return nil, io.EOF
+		}
+
+		select {
+		case <-r.notify:
+		case <-r.done:
+		}
+	}
+}
+
+// Close marks the end of the audio. Buffers that were already written can
+// still be read. If `err` is not nil, it is returned by `Read` once they
+// have been consumed. Only the first call has any effect.
+func (r *RingBuffer) Close(err error) {
+	r.doneOnce.Do(func() {
+		r.err = err
+		atomic.StoreInt32(&r.closed, 1)
+		close(r.done)
+	})
+}
+
+// Len returns the number of buffers waiting to be read.
+func (r *RingBuffer) Len() int {
+	// read never overtakes write, so load it first to avoid underflowing
+	rd := atomic.LoadUint64(&r.read)
+	return int(atomic.LoadUint64(&r.write) - rd)
+}
+
+// Cap returns the maximum number of buffers that can be held.
+func (r *RingBuffer) Cap() int {
+	return len(r.slots)
+}
+
+// Written returns the total number of buffers written.
+func (r *RingBuffer) Written() uint64 {
+	return atomic.LoadUint64(&r.write)
+}
+
+// Dropped returns the number of buffers that were discarded because the
+// buffer was full (only with `OverflowDropOldest`).
+func (r *RingBuffer) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+// wake signals whoever is waiting on the other end, without blocking.
+func (r *RingBuffer) wake() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// isClosed returns whether `Close` has been called.
+func (r *RingBuffer) isClosed() bool {
+	return atomic.LoadInt32(&r.closed) == 1
+}
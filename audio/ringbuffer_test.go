@@ -0,0 +1,124 @@
+package audio_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// frame returns a buffer whose samples are all `v`, so that it can be
+// identified after passing through a ring buffer.
+func frame(v int16) []int16 {
+	return []int16{v, v, v, v}
+}
+
+func TestRingBuffer(t *testing.T) {
+	r := audio.NewRingBuffer(4, audio.OverflowBlock)
+	require.Equal(t, 4, r.Cap())
+
+	in := frame(1)
+	require.Nil(t, r.Write(in))
+	require.Nil(t, r.Write(frame(2)))
+	in[0] = 100 // the buffer keeps a copy
+	require.Equal(t, 2, r.Len())
+
+	out, err := r.Read()
+	require.Nil(t, err)
+	require.Equal(t, frame(1), out)
+
+	r.Close(nil)
+	require.Equal(t, io.ErrClosedPipe, r.Write(frame(3)))
+	out, err = r.Read()
+	require.Nil(t, err)
+	require.Equal(t, frame(2), out)
+	_, err = r.Read()
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, uint64(2), r.Written())
+	require.Equal(t, 0, r.Len())
+
+	require.Equal(t, audio.DefaultRingBufferSize, audio.NewRingBuffer(0, audio.OverflowBlock).Cap())
+}
+
+// A full buffer blocks the writer until the reader catches up, and a reader
+// waits for the writer.
+func TestRingBufferBlock(t *testing.T) {
+	r := audio.NewRingBuffer(2, audio.OverflowBlock)
+	go func() {
+		for i := int16(0); i < 100; i++ {
+			r.Write(frame(i))
+		}
+		r.Close(nil)
+	}()
+
+	for i := int16(0); i < 100; i++ {
+		if i%10 == 0 {
+			time.Sleep(time.Millisecond)
+			require.True(t, r.Len() <= 2)
+		}
+		out, err := r.Read()
+		require.Nil(t, err)
+		require.Equal(t, frame(i), out)
+	}
+	_, err := r.Read()
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, uint64(0), r.Dropped())
+}
+
+func TestRingBufferDropOldest(t *testing.T) {
+	r := audio.NewRingBuffer(3, audio.OverflowDropOldest)
+	for i := int16(0); i < 10; i++ {
+		require.Nil(t, r.Write(frame(i)))
+	}
+	require.Equal(t, uint64(7), r.Dropped())
+	require.Equal(t, 3, r.Len())
+	for i := int16(7); i < 10; i++ {
+		out, err := r.Read()
+		require.Nil(t, err)
+		require.Equal(t, frame(i), out)
+	}
+
+	// a concurrent reader always sees buffers in order, and every buffer is
+	// either read or dropped
+	r = audio.NewRingBuffer(3, audio.OverflowDropOldest)
+	go func() {
+		for i := int16(0); i < 1000; i++ {
+			r.Write(frame(i))
+		}
+		r.Close(nil)
+	}()
+	last, read := int16(-1), 0
+	for {
+		out, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.Equal(t, out[0], out[3])
+		require.True(t, out[0] > last)
+		last = out[0]
+		read++
+	}
+	require.Equal(t, int16(999), last)
+	require.Equal(t, uint64(1000), uint64(read)+r.Dropped())
+}
+
+func TestRingBufferError(t *testing.T) {
+	r := audio.NewRingBuffer(2, audio.OverflowError)
+	require.Nil(t, r.Write(frame(1)))
+	require.Nil(t, r.Write(frame(2)))
+	err := r.Write(frame(3))
+	require.NotNil(t, err)
+	require.Equal(t, errors.AudioFileBufferOverflow, err.(*errors.Error).Code)
+
+	// the buffered audio is still read before the error that closed it
+	r.Close(err)
+	out, readErr := r.Read()
+	require.Nil(t, readErr)
+	require.Equal(t, frame(1), out)
+	r.Read()
+	_, readErr = r.Read()
+	require.Equal(t, err, readErr)
+}
@@ -0,0 +1,185 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Samples decodes the raw audio data into floating point samples in the
+// range [-1, 1). The result is indexed by channel first, and then by sample,
+// so `Samples()[0][i]` is the i'th sample of the first channel. 8, 16, 24 and
+// 32-bit PCM data is supported.
+func (w *WAV) Samples() [][]float64 {
+	numChannels := int(w.NumChannels)
+	sampleSize := int(w.BitsPerSample / 8)
+	if numChannels == 0 || sampleSize == 0 {
+		return nil
+	}
+
+	frameSize := numChannels * sampleSize
+	numFrames := len(w.audioData) / frameSize
+
+	channels := make([][]float64, numChannels)
+	for c := range channels {
+		channels[c] = make([]float64, numFrames)
+	}
+
+	for i := 0; i < numFrames; i++ {
+		for c := 0; c < numChannels; c++ {
+			off := i*frameSize + c*sampleSize
+			channels[c][i] = decodeSample(w.audioData[off:off+sampleSize], sampleSize)
+		}
+	}
+	return channels
+}
+
+// SetSamples replaces the raw audio data with the given floating point
+// samples, encoding them using the WAV's current bit depth. The samples must
+// be indexed by channel first (as returned by `Samples`) and every channel
+// should have the same length. Values outside of [-1, 1) are clipped.
+func (w *WAV) SetSamples(channels [][]float64) {
+	numChannels := len(channels)
+	sampleSize := int(w.BitsPerSample / 8)
+	if numChannels == 0 || sampleSize == 0 {
+		w.audioData = make([]byte, 0)
+		return
+	}
+
+	numFrames := len(channels[0])
+	frameSize := numChannels * sampleSize
+	data := make([]byte, numFrames*frameSize)
+	for i := 0; i < numFrames; i++ {
+		for c := 0; c < numChannels; c++ {
+			off := i*frameSize + c*sampleSize
+			encodeSample(data[off:off+sampleSize], sampleSize, channels[c][i])
+		}
+	}
+
+	w.NumChannels = uint16(numChannels)
+	w.audioData = data
+}
+
+// decodeSamples converts interleaved PCM data with samples of the given size
+// (in bytes) to floats, without separating the channels.
+func decodeSamples(data []byte, sampleSize int) []float64 {
+	if sampleSize == 0 {
+		return nil
+	}
+	out := make([]float64, len(data)/sampleSize)
+	for i := range out {
+		out[i] = decodeSample(data[i*sampleSize:(i+1)*sampleSize], sampleSize)
+	}
+	return out
+}
+
+// decodeSample converts a single little-endian PCM sample of the given size
+// (in bytes) to a float in the range [-1, 1). 8-bit samples are unsigned, as
+// specified by the WAV format.
+func decodeSample(b []byte, sampleSize int) float64 {
+	switch sampleSize {
+	case 1:
+		return (float64(b[0]) - 128) / 128
+	case 2:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case 3:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / (1 << 23)
+	case 4:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+	return 0
+}
+
+// encodeSample is the inverse of decodeSample. It writes the given value into
+// `b` as a little-endian PCM sample of the given size (in bytes).
+func encodeSample(b []byte, sampleSize int, v float64) {
+	switch sampleSize {
+	case 1:
+		b[0] = byte(quantize(v, 1<<7) + 128)
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(int16(quantize(v, 1<<15))))
+	case 3:
+		q := int32(quantize(v, 1<<23))
+		b[0] = byte(q)
+		b[1] = byte(q >> 8)
+		b[2] = byte(q >> 16)
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(int32(quantize(v, 1<<31))))
+	}
+}
+
+// quantize scales a float in [-1, 1) to an integer in [-scale, scale),
+// clipping values that fall outside of the range.
+func quantize(v float64, scale float64) int64 {
+	q := math.Floor(v*scale + 0.5)
+	if q > scale-1 {
+		q = scale - 1
+	}
+	if q < -scale {
+		q = -scale
+	}
+	return int64(q)
+}
+
+// int16ToFloat converts 16-bit samples (as read from PortAudio) to floats
+// in the range [-1, 1).
+func int16ToFloat(in []int16) []float64 {
+	out := make([]float64, len(in))
+	for i, v := range in {
+		out[i] = float64(v) / (1 << 15)
+	}
+	return out
+}
+
+// floatToInt16 is the inverse of int16ToFloat.
+func floatToInt16(in []float64) []int16 {
+	out := make([]int16, len(in))
+	for i, v := range in {
+		out[i] = int16(quantize(v, 1<<15))
+	}
+	return out
+}
+
+// mixDown averages all of the channels into a single mono channel.
+func mixDown(channels [][]float64) []float64 {
+	if len(channels) == 0 {
+		return nil
+	}
+	if len(channels) == 1 {
+		return channels[0]
+	}
+	out := make([]float64, len(channels[0]))
+	for _, ch := range channels {
+		for i, v := range ch {
+			out[i] += v
+		}
+	}
+	for i := range out {
+		out[i] /= float64(len(channels))
+	}
+	return out
+}
+
+// resample converts a single channel of audio from one sample rate to another
+// using linear interpolation. It is intended for aligning signals (such as a
+// playback reference) rather than for high fidelity conversion.
+func resample(in []float64, from uint32, to uint32) []float64 {
+	if from == to || from == 0 || to == 0 || len(in) == 0 {
+		return in
+	}
+
+	ratio := float64(from) / float64(to)
+	n := int(float64(len(in)) / ratio)
+	out := make([]float64, n)
+	for i := range out {
+		pos := float64(i) * ratio
+		j := int(pos)
+		frac := pos - float64(j)
+		if j+1 < len(in) {
+			out[i] = in[j]*(1-frac) + in[j+1]*frac
+		} else {
+			out[i] = in[len(in)-1]
+		}
+	}
+	return out
+}
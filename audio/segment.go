@@ -0,0 +1,124 @@
+package audio
+
+import (
+	"math"
+)
+
+const (
+	// DefaultSilenceThreshold is the level (relative to full scale) below
+	// which audio is considered silent. It is equivalent to `SilentThresh`
+	// for 16-bit audio.
+	DefaultSilenceThreshold = float64(SilentThresh) / (1 << 15)
+	// DefaultSegmentMinSilence is the default amount of silence (in seconds)
+	// that separates two utterances.
+	DefaultSegmentMinSilence = 0.5
+	// DefaultSegmentMinLength is the default length (in seconds) of the
+	// shortest utterance that is kept. Anything shorter is treated as noise.
+	DefaultSegmentMinLength = 0.25
+	// DefaultSegmentPadding is the default amount of audio (in seconds) kept
+	// on either side of each utterance, so that words aren't cut off abruptly.
+	DefaultSegmentPadding = 0.1
+
+	// segmentFrameLen is the length (in seconds) of each frame examined by
+	// the voice activity detector. It matches the frames that the recording
+	// framework examines.
+	segmentFrameLen = float64(BufSize) / SampleRate
+)
+
+// SegmentParams configures how `File.Segment` splits audio into utterances.
+type SegmentParams struct {
+	// MinSilence is how much silence (in seconds) must separate two
+	// utterances for them to be split.
+	MinSilence float64
+	// MinLength is the length (in seconds) of the shortest utterance to keep.
+	MinLength float64
+	// Padding is how much audio (in seconds) to keep on either side of each
+	// utterance.
+	Padding float64
+	// Threshold is the level (relative to full scale) below which audio is
+	// considered silent.
+	Threshold float64
+}
+
+// NewSegmentParams creates the default set of SegmentParams. You should
+// call this function to get the default and then replace the ones you want
+// to customize.
+func NewSegmentParams() *SegmentParams {
+	return &SegmentParams{
+		MinSilence: DefaultSegmentMinSilence,
+		MinLength:  DefaultSegmentMinLength,
+		Padding:    DefaultSegmentPadding,
+		Threshold:  DefaultSilenceThreshold,
+	}
+}
+
+// Segment is a region of speech within a longer piece of audio.
+type Segment struct {
+	// Start and End are the position (in seconds) of the segment within the
+	// original audio, including padding.
+	Start float64
+	End   float64
+	// Audio is the audio of the segment.
+	Audio *File
+}
+
+// Segment splits the audio into utterances separated by silence, using the
+// same voice activity detection as the recording framework. It returns the
+// segments in order. Pass `nil` to use the default parameters.
+func (f *File) Segment(params *SegmentParams) []*Segment {
+	if params == nil {
+		params = NewSegmentParams()
+	}
+
+	rate := float64(f.AudioData.SampleRate)
+	mono := mixDown(f.AudioData.Samples())
+	frameLen := int(math.Max(1, math.Round(segmentFrameLen*rate)))
+	minSilence := int(math.Ceil(params.MinSilence / segmentFrameLen))
+
+	// find [start, end) sample ranges of speech, where a range ends once
+	// there has been enough silence after it
+	regions := make([][2]int, 0)
+	start, end, silentFrames := -1, 0, 0
+	for i := 0; i < len(mono); i += frameLen {
+		frameEnd := int(math.Min(float64(i+frameLen), float64(len(mono))))
+		if !isSilentFrame(mono[i:frameEnd], params.Threshold) {
+			if start < 0 {
+				start = i
+			}
+			end = frameEnd
+			silentFrames = 0
+			continue
+		}
+
+		silentFrames++
+		if start >= 0 && silentFrames >= minSilence {
+			regions = append(regions, [2]int{start, end})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		regions = append(regions, [2]int{start, end})
+	}
+
+	// drop anything too short, pad and slice out the rest
+	padding := int(math.Round(params.Padding * rate))
+	minLength := int(math.Round(params.MinLength * rate))
+	segments := make([]*Segment, 0, len(regions))
+	for _, r := range regions {
+		if r[1]-r[0] < minLength {
+			continue
+		}
+		s := int(math.Max(0, float64(r[0]-padding)))
+		e := int(math.Min(float64(len(mono)), float64(r[1]+padding)))
+		audio, err := f.SliceSamples(s, e)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, &Segment{
+			Start: float64(s) / rate,
+			End:   float64(e) / rate,
+			Audio: audio,
+		})
+	}
+	return segments
+}
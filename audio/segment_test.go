@@ -0,0 +1,65 @@
+package audio_test
+
+import (
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// utterances builds a recording with tones (standing in for speech) at the
+// given [start, end) times in seconds, with silence in between.
+func utterances(rate uint32, length float64, times ...[2]float64) []float64 {
+	s := make([]float64, int(length*float64(rate)))
+	for _, t := range times {
+		tone := testutils.Sine(300, 0.5, rate, int((t[1]-t[0])*float64(rate)))
+		copy(s[int(t[0]*float64(rate)):], tone)
+	}
+	return s
+}
+
+func TestSegment(t *testing.T) {
+	f := testutils.NewFile(16000, utterances(16000, 10, [2]float64{1, 2}, [2]float64{3, 4.5}, [2]float64{7, 8}))
+	segments := f.Segment(nil)
+	require.Len(t, segments, 3)
+
+	expected := [][2]float64{{1, 2}, {3, 4.5}, {7, 8}}
+	for i, s := range segments {
+		// frames are 64ms, and there is 100ms of padding on either side
+		require.InDelta(t, expected[i][0]-audio.DefaultSegmentPadding, s.Start, 0.07)
+		require.InDelta(t, expected[i][1]+audio.DefaultSegmentPadding, s.End, 0.07)
+		require.InDelta(t, s.End-s.Start, s.Audio.Duration(), 1e-9)
+	}
+}
+
+// Utterances separated by less than the minimum silence are merged, and
+// utterances shorter than the minimum length are dropped.
+func TestSegmentParams(t *testing.T) {
+	f := testutils.NewFile(16000, utterances(16000, 6, [2]float64{1, 2}, [2]float64{2.4, 3}, [2]float64{4, 4.1}))
+
+	segments := f.Segment(nil)
+	require.Len(t, segments, 1)
+	require.InDelta(t, 0.9, segments[0].Start, 0.07)
+	require.InDelta(t, 3.1, segments[0].End, 0.07)
+
+	params := audio.NewSegmentParams()
+	params.MinSilence = 0.25
+	params.MinLength = 0.05
+	params.Padding = 0
+	segments = f.Segment(params)
+	require.Len(t, segments, 3)
+	require.InDelta(t, 4, segments[2].Start, 0.07)
+}
+
+// Speech running to the very end of the audio is still returned, and padding
+// doesn't run past the edges.
+func TestSegmentEdges(t *testing.T) {
+	f := testutils.NewFile(8000, utterances(8000, 2, [2]float64{0, 0.5}, [2]float64{1.5, 2}))
+	segments := f.Segment(nil)
+	require.Len(t, segments, 2)
+	require.Equal(t, 0.0, segments[0].Start)
+	require.Equal(t, 2.0, segments[1].End)
+
+	require.Len(t, testutils.NewFile(8000, make([]float64, 8000)).Segment(nil), 0)
+}
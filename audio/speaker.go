@@ -0,0 +1,420 @@
+package audio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+const (
+	// DefaultSpeakerThreshold is the default lowest score (see
+	// `Voiceprint.Score`) accepted as a match.
+	DefaultSpeakerThreshold = -1.0
+	// DefaultSpeakerComponents is the default number of Gaussians in each
+	// voiceprint.
+	DefaultSpeakerComponents = 8
+
+	// speakerIterations is the number of EM iterations used to train a
+	// voiceprint.
+	speakerIterations = 20
+	// speakerVarianceFloor stops a Gaussian collapsing onto a few frames.
+	speakerVarianceFloor = 1e-2
+	// speakerEnergyRange is how far (in natural log units of the mean
+	// filterbank energy, ~30dB) below the loudest frame a frame may be and
+	// still count as speech.
+	speakerEnergyRange = 6.9
+	// speakerSampleRate is the sample rate that audio is converted to before
+	// features are extracted, so that voiceprints can be compared with speech
+	// recorded at a different rate.
+	speakerSampleRate = 16000
+	// speakerFeatureDim is the number of features per frame: the MFCCs
+	// without the energy coefficient, and their deltas.
+	speakerFeatureDim = 2 * (DefaultFeatureCoefficients - 1)
+)
+
+// SpeakerVerifier decides whether speech was spoken by one of a set of
+// enrolled users.
+type SpeakerVerifier interface {
+	// Verify compares the speech to every enrolled user and returns the
+	// closest match.
+	Verify(f *File) *SpeakerMatch
+}
+
+// SpeakerMatch is the result of speaker verification.
+type SpeakerMatch struct {
+	// Name is the name of the enrolled user whose voice is closest, or empty
+	// if nobody is enrolled.
+	Name string
+	// Score is how well the speech matches that user's voice (higher is
+	// better).
+	Score float64
+	// Accepted is whether the score is good enough to treat the speech as
+	// spoken by that user.
+	Accepted bool
+}
+
+// Voiceprint is a model of an enrolled user's voice: a Gaussian mixture
+// model (with diagonal covariances) over MFCCs and their deltas, along with
+// how well the enrollment samples fit it (the baseline), which is used to
+// normalise scores. It can be serialised to JSON.
+type Voiceprint struct {
+	Name      string      `json:"name"`
+	Weights   []float64   `json:"weights"`
+	Means     [][]float64 `json:"means"`
+	Variances [][]float64 `json:"variances"`
+	Baseline  float64     `json:"baseline"`
+}
+
+// NewVoiceprint trains a voiceprint for the named user from one or more
+// recordings of them speaking (a total of 10s or more works best). It
+// returns an error if the recordings contain no speech.
+func NewVoiceprint(name string, samples ...*File) (*Voiceprint, error) {
+	features := make([][][]float64, 0, len(samples))
+	all := make([][]float64, 0)
+	for _, s := range samples {
+		if f := speakerFeatures(s); len(f) > 0 {
+			features = append(features, f)
+			all = append(all, f...)
+		}
+	}
+	if len(all) < DefaultSpeakerComponents {
+		return nil, errors.NewFromErrorCodeInfo(errors.SpeakerNoSpeech, fmt.Sprintf("Found %d frames of speech for %q.", len(all), name))
+	}
+
+	v := trainGMM(all, DefaultSpeakerComponents)
+	v.Name = name
+
+	// scores are relative to how well the enrollment samples fit
+	scores := make([]float64, len(features))
+	for i, f := range features {
+		scores[i] = v.logLikelihood(f)
+	}
+	v.Baseline = mean(scores)
+	return v, nil
+}
+
+// Score returns how well the speech matches the voiceprint: the difference
+// between the average log-likelihood of its frames and that of the enrollment
+// samples, per feature. It is close to 0 for the enrolled user, and negative
+// for speech that fits worse. It returns negative infinity if there is no
+// speech.
+func (v *Voiceprint) Score(f *File) float64 {
+	return v.score(speakerFeatures(f))
+}
+
+// validate checks that the voiceprint is a well-formed model of speaker
+// features: every component has a weight, and a mean and variances with one
+// value per feature, the variances are positive and the baseline is finite
+// (so that scores are finite).
+func (v *Voiceprint) validate() error {
+	if v == nil {
+		return errors.NewFromErrorCodeInfo(errors.SpeakerInvalidVoiceprint, "The voiceprint is null.")
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return errors.NewFromErrorCodeInfo(errors.SpeakerInvalidVoiceprint, fmt.Sprintf("Voiceprint %q: ", v.Name)+fmt.Sprintf(format, args...))
+	}
+
+	k := len(v.Weights)
+	if k == 0 {
+		return invalid("it has no components.")
+	}
+	if len(v.Means) != k || len(v.Variances) != k {
+		return invalid("it has %d weights, %d means and %d variances.", k, len(v.Means), len(v.Variances))
+	}
+	if math.IsNaN(v.Baseline) || math.IsInf(v.Baseline, 0) {
+		return invalid("it has baseline %v.", v.Baseline)
+	}
+	dim := speakerFeatureDim
+	for i := 0; i < k; i++ {
+		if v.Weights[i] < 0 || math.IsNaN(v.Weights[i]) || math.IsInf(v.Weights[i], 0) {
+			return invalid("component %d has weight %v.", i, v.Weights[i])
+		}
+		if len(v.Means[i]) != dim || len(v.Variances[i]) != dim {
+			return invalid("component %d has a mean of dimension %d and variances of dimension %d, but expected %d.", i, len(v.Means[i]), len(v.Variances[i]), dim)
+		}
+		for j := 0; j < dim; j++ {
+			if math.IsNaN(v.Means[i][j]) || math.IsInf(v.Means[i][j], 0) {
+				return invalid("component %d has mean %v.", i, v.Means[i][j])
+			}
+			if !(v.Variances[i][j] > 0) || math.IsInf(v.Variances[i][j], 0) {
+				return invalid("component %d has variance %v.", i, v.Variances[i][j])
+			}
+		}
+	}
+	return nil
+}
+
+// score computes the score of already extracted features.
+func (v *Voiceprint) score(features [][]float64) float64 {
+	if len(features) == 0 {
+		return math.Inf(-1)
+	}
+	return (v.logLikelihood(features) - v.Baseline) / float64(len(features[0]))
+}
+
+// GMMVerifier is a SpeakerVerifier that compares speech against the
+// voiceprints of enrolled users. It is a simple offline baseline, so it works
+// best when enrollment and verification are done in similar conditions.
+//
+// It is safe to enroll users while verifying in another goroutine.
+type GMMVerifier struct {
+	// Threshold is the lowest score accepted as a match (see
+	// `DefaultSpeakerThreshold`).
+	Threshold float64
+
+	mu          sync.RWMutex
+	voiceprints []*Voiceprint
+}
+
+// NewGMMVerifier creates a verifier with the given threshold and no enrolled
+// users.
+func NewGMMVerifier(threshold float64) *GMMVerifier {
+	return &GMMVerifier{Threshold: threshold}
+}
+
+// LoadGMMVerifier creates a verifier with the given threshold and the
+// voiceprints previously written by `Save`. It returns an error if any of the
+// voiceprints is malformed.
+func LoadGMMVerifier(r io.Reader, threshold float64) (*GMMVerifier, error) {
+	v := NewGMMVerifier(threshold)
+	if err := json.NewDecoder(r).Decode(&v.voiceprints); err != nil {
+		return nil, err
+	}
+	for _, vp := range v.voiceprints {
+		if err := vp.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Enroll trains a voiceprint for the named user from recordings of them
+// speaking (see `NewVoiceprint`) and adds it to the verifier, replacing any
+// existing voiceprint with the same name.
+func (v *GMMVerifier) Enroll(name string, samples ...*File) error {
+	vp, err := NewVoiceprint(name, samples...)
+	if err != nil {
+		return err
+	}
+	return v.Add(vp)
+}
+
+// Add adds a voiceprint to the verifier, replacing any existing voiceprint
+// with the same name. It returns an error if the voiceprint is malformed.
+func (v *GMMVerifier) Add(vp *Voiceprint) error {
+	if err := vp.validate(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, existing := range v.voiceprints {
+		if existing.Name == vp.Name {
+			v.voiceprints[i] = vp
+			return nil
+		}
+	}
+	v.voiceprints = append(v.voiceprints, vp)
+	return nil
+}
+
+// Remove removes the named user's voiceprint, if there is one.
+func (v *GMMVerifier) Remove(name string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, vp := range v.voiceprints {
+		if vp.Name == name {
+			v.voiceprints = append(v.voiceprints[:i], v.voiceprints[i+1:]...)
+			return
+		}
+	}
+}
+
+// Names returns the names of the enrolled users.
+func (v *GMMVerifier) Names() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	names := make([]string, len(v.voiceprints))
+	for i, vp := range v.voiceprints {
+		names[i] = vp.Name
+	}
+	return names
+}
+
+// Save writes the enrolled voiceprints to `w` as JSON, so that they can be
+// loaded with `LoadGMMVerifier`.
+func (v *GMMVerifier) Save(w io.Writer) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return json.NewEncoder(w).Encode(v.voiceprints)
+}
+
+// Verify scores the speech against every enrolled voiceprint and returns the
+// best match.
+func (v *GMMVerifier) Verify(f *File) *SpeakerMatch {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	match := &SpeakerMatch{Score: math.Inf(-1)}
+	features := speakerFeatures(f)
+	if len(features) == 0 {
+		return match
+	}
+	for _, vp := range v.voiceprints {
+		if score := vp.score(features); score > match.Score {
+			match.Name, match.Score = vp.Name, score
+		}
+	}
+	match.Accepted = match.Name != "" && match.Score >= v.Threshold
+	return match
+}
+
+// logLikelihood returns the average log-likelihood of the frames under the
+// voiceprint's mixture model.
+func (v *Voiceprint) logLikelihood(features [][]float64) float64 {
+	total := 0.0
+	for _, x := range features {
+		total += logSumExp(v.componentLogLikelihoods(x))
+	}
+	return total / float64(len(features))
+}
+
+// componentLogLikelihoods returns the weighted log-likelihood of a frame
+// under each component of the mixture.
+func (v *Voiceprint) componentLogLikelihoods(x []float64) []float64 {
+	out := make([]float64, len(v.Weights))
+	for k := range v.Weights {
+		ll := math.Log(v.Weights[k])
+		for d, value := range x {
+			diff := value - v.Means[k][d]
+			ll -= 0.5 * (math.Log(2*math.Pi*v.Variances[k][d]) + diff*diff/v.Variances[k][d])
+		}
+		out[k] = ll
+	}
+	return out
+}
+
+// trainGMM fits a Gaussian mixture model with `k` diagonal components to the
+// data with expectation maximisation. The components start at evenly spaced
+// frames so that training is deterministic.
+func trainGMM(data [][]float64, k int) *Voiceprint {
+	dims := len(data[0])
+	v := &Voiceprint{
+		Weights:   make([]float64, k),
+		Means:     make([][]float64, k),
+		Variances: make([][]float64, k),
+	}
+
+	// start with the overall variance around evenly spaced frames
+	overall := make([]float64, dims)
+	for d := range overall {
+		values := make([]float64, len(data))
+		for i, x := range data {
+			values[i] = x[d]
+		}
+		m := mean(values)
+		for _, value := range values {
+			overall[d] += (value - m) * (value - m)
+		}
+		overall[d] = math.Max(overall[d]/float64(len(data)), speakerVarianceFloor)
+	}
+	for c := 0; c < k; c++ {
+		v.Weights[c] = 1 / float64(k)
+		v.Means[c] = append([]float64(nil), data[c*len(data)/k]...)
+		v.Variances[c] = append([]float64(nil), overall...)
+	}
+
+	resp := make([][]float64, len(data))
+	for iter := 0; iter < speakerIterations; iter++ {
+		// expectation: how responsible each component is for each frame
+		for i, x := range data {
+			ll := v.componentLogLikelihoods(x)
+			norm := logSumExp(ll)
+			resp[i] = make([]float64, k)
+			for c := range ll {
+				resp[i][c] = math.Exp(ll[c] - norm)
+			}
+		}
+
+		// maximisation: re-estimate each component from its frames
+		for c := 0; c < k; c++ {
+			weight := 0.0
+			m := make([]float64, dims)
+			for i, x := range data {
+				weight += resp[i][c]
+				for d := range m {
+					m[d] += resp[i][c] * x[d]
+				}
+			}
+			if weight < 1e-9 {
+				// nothing belongs to this component, so leave it be
+				continue
+			}
+			variance := make([]float64, dims)
+			for d := range m {
+				m[d] /= weight
+			}
+			for i, x := range data {
+				for d := range variance {
+					diff := x[d] - m[d]
+					variance[d] += resp[i][c] * diff * diff
+				}
+			}
+			for d := range variance {
+				variance[d] = math.Max(variance[d]/weight, speakerVarianceFloor)
+			}
+			v.Weights[c] = weight / float64(len(data))
+			v.Means[c] = m
+			v.Variances[c] = variance
+		}
+	}
+	return v
+}
+
+// speakerFeatures returns the MFCCs (without the energy coefficient) and
+// their deltas for the frames of the audio that contain speech.
+func speakerFeatures(f *File) [][]float64 {
+	samples := resampleBandlimited(mixDown(f.AudioData.Samples()), f.AudioData.SampleRate, speakerSampleRate)
+	frames := NewFeatureExtractor(speakerSampleRate, NewFeatureParams()).Push(samples)
+	if len(frames) == 0 {
+		return nil
+	}
+
+	mfccs := make([][]float64, len(frames))
+	energies := make([]float64, len(frames))
+	maxEnergy := math.Inf(-1)
+	for i, frame := range frames {
+		mfccs[i] = frame.MFCC[1:]
+		energies[i] = mean(frame.LogMel)
+		maxEnergy = math.Max(maxEnergy, energies[i])
+	}
+	deltas := Deltas(mfccs, DefaultDeltaWidth)
+
+	features := make([][]float64, 0, len(frames))
+	for i := range frames {
+		if energies[i] >= maxEnergy-speakerEnergyRange && energies[i] > math.Log(featureFloor) {
+			features = append(features, append(append([]float64(nil), mfccs[i]...), deltas[i]...))
+		}
+	}
+	return features
+}
+
+// logSumExp returns log(sum(exp(x))) without overflowing.
+func logSumExp(x []float64) float64 {
+	max := math.Inf(-1)
+	for _, v := range x {
+		max = math.Max(max, v)
+	}
+	if math.IsInf(max, -1) {
+		return max
+	}
+	sum := 0.0
+	for _, v := range x {
+		sum += math.Exp(v - max)
+	}
+	return max + math.Log(sum)
+}
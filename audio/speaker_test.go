@@ -0,0 +1,177 @@
+package audio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// voice synthesises "speech" from a made-up speaker: syllables at random
+// pitches around the speaker's own, whose harmonics are shaped by the
+// speaker's formants, separated by short pauses.
+func voice(pitch float64, formants []float64, seconds float64, seed int64) *audio.File {
+	const rate = 16000
+	rng := rand.New(rand.NewSource(seed))
+	out := make([]float64, 0)
+	for float64(len(out)) < seconds*rate {
+		f0 := pitch * (0.9 + 0.2*rng.Float64())
+		n := int((0.15 + 0.15*rng.Float64()) * rate)
+		syllable := make([]float64, n)
+		for h := f0; h < rate/2; h += f0 {
+			// harmonics near a formant are loud
+			amp := 0.0
+			for _, f := range formants {
+				d := (h - f) / 150
+				amp += 0.2 / (1 + d*d)
+			}
+			for i, v := range testutils.Sine(h, amp, rate, n) {
+				syllable[i] += v
+			}
+		}
+		out = append(out, syllable...)
+		out = append(out, make([]float64, int(0.1*rate))...)
+	}
+	noise := testutils.Noise(0.001, len(out), seed)
+	for i := range out {
+		out[i] += noise[i]
+	}
+	return testutils.NewFile(rate, out)
+}
+
+func TestGMMVerifier(t *testing.T) {
+	alice := []float64{700, 1200, 2600}
+	bob := []float64{400, 2000, 3000}
+
+	v := audio.NewGMMVerifier(audio.DefaultSpeakerThreshold)
+	require.False(t, v.Verify(voice(210, alice, 2, 10)).Accepted)
+
+	require.Nil(t, v.Enroll("alice", voice(210, alice, 4, 1), voice(210, alice, 4, 2)))
+	require.Equal(t, []string{"alice"}, v.Names())
+
+	// a new utterance from alice is accepted, but bob isn't
+	match := v.Verify(voice(210, alice, 2, 10))
+	require.True(t, match.Accepted, "score %v", match.Score)
+	require.Equal(t, "alice", match.Name)
+	match = v.Verify(voice(120, bob, 2, 11))
+	require.False(t, match.Accepted, "score %v", match.Score)
+
+	// once bob is enrolled, each is identified
+	require.Nil(t, v.Enroll("bob", voice(120, bob, 4, 3), voice(120, bob, 4, 4)))
+	require.Equal(t, "bob", v.Verify(voice(120, bob, 2, 11)).Name)
+	require.Equal(t, "alice", v.Verify(voice(210, alice, 2, 12)).Name)
+
+	v.Remove("bob")
+	require.Equal(t, []string{"alice"}, v.Names())
+}
+
+// Voiceprints survive a round trip through JSON.
+func TestGMMVerifierSave(t *testing.T) {
+	v := audio.NewGMMVerifier(audio.DefaultSpeakerThreshold)
+	require.Nil(t, v.Enroll("carol", voice(180, []float64{600, 1700, 2500}, 4, 5)))
+
+	buf := new(bytes.Buffer)
+	require.Nil(t, v.Save(buf))
+	loaded, err := audio.LoadGMMVerifier(buf, audio.DefaultSpeakerThreshold)
+	require.Nil(t, err)
+	require.Equal(t, []string{"carol"}, loaded.Names())
+
+	utterance := voice(180, []float64{600, 1700, 2500}, 2, 6)
+	require.InDelta(t, v.Verify(utterance).Score, loaded.Verify(utterance).Score, 1e-9)
+
+	_, err = audio.LoadGMMVerifier(bytes.NewBufferString("not json"), 0)
+	require.NotNil(t, err)
+}
+
+// Speech recorded at a different rate from the enrollment still matches.
+func TestGMMVerifierSampleRate(t *testing.T) {
+	alice := []float64{700, 1200, 2600}
+	v := audio.NewGMMVerifier(audio.DefaultSpeakerThreshold)
+	require.Nil(t, v.Enroll("alice", voice(210, alice, 4, 1), voice(210, alice, 4, 2)))
+
+	utterance := voice(210, alice, 2, 10)
+	expected := v.Verify(utterance).Score
+	for _, rate := range []uint32{22050, 44100} {
+		converted, err := utterance.ConvertTo(rate, 1, 16)
+		require.Nil(t, err)
+		match := v.Verify(converted)
+		require.True(t, match.Accepted, "%vHz: score %v", rate, match.Score)
+		require.InDelta(t, expected, match.Score, 0.5, "%vHz", rate)
+	}
+}
+
+// voiceprint returns a well-formed voiceprint with two components, to be
+// corrupted by tests.
+func voiceprint() *audio.Voiceprint {
+	vp := &audio.Voiceprint{Name: "a", Weights: []float64{0.5, 0.5}}
+	for k := 0; k < 2; k++ {
+		variances := make([]float64, 24)
+		for i := range variances {
+			variances[i] = 1
+		}
+		vp.Means = append(vp.Means, make([]float64, 24))
+		vp.Variances = append(vp.Variances, variances)
+	}
+	return vp
+}
+
+// Malformed voiceprints are rejected, whether they are loaded or built in
+// code.
+func TestGMMVerifierInvalidVoiceprint(t *testing.T) {
+	for name, corrupt := range map[string]func(vp *audio.Voiceprint){
+		"no components":     func(vp *audio.Voiceprint) { vp.Weights, vp.Means, vp.Variances = nil, nil, nil },
+		"missing means":     func(vp *audio.Voiceprint) { vp.Means = vp.Means[:1] },
+		"missing variances": func(vp *audio.Voiceprint) { vp.Variances = vp.Variances[:1] },
+		"ragged means":      func(vp *audio.Voiceprint) { vp.Means[1] = vp.Means[1][:23] },
+		"ragged variances":  func(vp *audio.Voiceprint) { vp.Variances[0] = vp.Variances[0][:23] },
+		"wrong dimension": func(vp *audio.Voiceprint) {
+			vp.Means = [][]float64{{0, 0, 0}, {1, 1, 1}}
+			vp.Variances = [][]float64{{1, 1, 1}, {1, 1, 1}}
+		},
+		"zero variance":     func(vp *audio.Voiceprint) { vp.Variances[1][5] = 0 },
+		"negative variance": func(vp *audio.Voiceprint) { vp.Variances[0][0] = -1 },
+		"negative weight":   func(vp *audio.Voiceprint) { vp.Weights[0] = -1 },
+		"infinite baseline": func(vp *audio.Voiceprint) { vp.Baseline = math.Inf(-1) },
+		"NaN mean":          func(vp *audio.Voiceprint) { vp.Means[0][0] = math.NaN() },
+	} {
+		vp := voiceprint()
+		corrupt(vp)
+		err := audio.NewGMMVerifier(0).Add(vp)
+		require.NotNil(t, err, name)
+		require.Equal(t, errors.SpeakerInvalidVoiceprint, err.(*errors.Error).Code, name)
+
+		// non-finite numbers can't be saved as JSON
+		data, jsonErr := json.Marshal([]*audio.Voiceprint{vp})
+		if jsonErr != nil {
+			continue
+		}
+		_, err = audio.LoadGMMVerifier(bytes.NewReader(data), 0)
+		require.NotNil(t, err, name)
+		require.Equal(t, errors.SpeakerInvalidVoiceprint, err.(*errors.Error).Code, name)
+	}
+
+	_, err := audio.LoadGMMVerifier(bytes.NewBufferString(`[null]`), 0)
+	require.NotNil(t, err)
+	require.Equal(t, errors.SpeakerInvalidVoiceprint, err.(*errors.Error).Code)
+	require.NotNil(t, audio.NewGMMVerifier(0).Add(nil))
+
+	// a well-formed voiceprint can be used
+	data, err := json.Marshal([]*audio.Voiceprint{voiceprint()})
+	require.Nil(t, err)
+	v, err := audio.LoadGMMVerifier(bytes.NewReader(data), 0)
+	require.Nil(t, err)
+	require.Equal(t, []string{"a"}, v.Names())
+	require.Equal(t, "a", v.Verify(voice(210, []float64{700, 1200, 2600}, 1, 1)).Name)
+}
+
+func TestVoiceprintNoSpeech(t *testing.T) {
+	_, err := audio.NewVoiceprint("nobody", testutils.NewFile(16000, make([]float64, 16000)))
+	require.NotNil(t, err)
+	require.Equal(t, errors.SpeakerNoSpeech, err.(*errors.Error).Code)
+}
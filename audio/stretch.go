@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+const (
+	// MinSpeed and MaxSpeed are the limits of the speed factor accepted by
+	// `TimeStretch`.
+	MinSpeed = 0.25
+	MaxSpeed = 4.0
+	// MaxPitchShift is the largest pitch shift (in semitones, in either
+	// direction) accepted by `PitchShift`.
+	MaxPitchShift = 24.0
+
+	// stretchFrameLen is the length (in seconds) of the segments that are
+	// overlap-added when stretching. It should cover a couple of pitch periods.
+	stretchFrameLen = 0.03
+	// stretchCorrelationStride is the step used when computing the cross
+	// correlation of candidate segments, trading accuracy for speed.
+	stretchCorrelationStride = 2
+)
+
+// TimeStretch returns a new audio file that plays at `speed` times the
+// original speed without changing the pitch (so 0.5 is half speed and 2 is
+// double speed). It uses WSOLA (waveform similarity overlap-add), which picks
+// each segment so that it lines up with the previous one, avoiding the
+// phasing artifacts of naive overlap-add. The speed must be between
+// `MinSpeed` and `MaxSpeed`.
+func (f *File) TimeStretch(speed float64) (*File, error) {
+	if speed < MinSpeed || speed > MaxSpeed || math.IsNaN(speed) {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidSpeed, fmt.Sprintf("Requested speed %f.", speed))
+	}
+	return f.withSamples(wsola(f.AudioData.Samples(), f.AudioData.SampleRate, speed)), nil
+}
+
+// PitchShift returns a new audio file with the pitch raised (or lowered, if
+// negative) by the given number of semitones, without changing the duration.
+// The audio is time-stretched by the pitch ratio and then resampled back to
+// its original length. The shift must be within `MaxPitchShift` semitones.
+func (f *File) PitchShift(semitones float64) (*File, error) {
+	if math.Abs(semitones) > MaxPitchShift || math.IsNaN(semitones) {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidSpeed, fmt.Sprintf("Requested pitch shift of %f semitones.", semitones))
+	}
+
+	ratio := math.Pow(2, semitones/12)
+	channels := wsola(f.AudioData.Samples(), f.AudioData.SampleRate, 1/ratio)
+	for c := range channels {
+		channels[c] = resampleRatio(channels[c], ratio)
+	}
+
+	// make sure rounding doesn't change the length
+	n := f.numFrames()
+	for c := range channels {
+		if len(channels[c]) < n {
+			channels[c] = append(channels[c], make([]float64, n-len(channels[c]))...)
+		}
+		channels[c] = channels[c][:n]
+	}
+	return f.withSamples(channels), nil
+}
+
+// wsola time-stretches the channels by the given speed factor. Segments of
+// the input are windowed and overlap-added at a fixed synthesis hop, while the
+// analysis position advances `speed` times as fast. Each segment is shifted
+// by up to a quarter of the segment length so that it best matches the audio
+// that naturally followed the previous segment. All channels use the same
+// shifts (chosen on the mono mix) so that they stay in phase.
+func wsola(channels [][]float64, sampleRate uint32, speed float64) [][]float64 {
+	if len(channels) == 0 || len(channels[0]) == 0 {
+		return channels
+	}
+
+	frameLen := int(stretchFrameLen * float64(sampleRate))
+	frameLen += frameLen % 2
+	if frameLen < 4 {
+		frameLen = 4
+	}
+	synthesisHop := frameLen / 2
+	tolerance := frameLen / 4
+	window := hann(frameLen)
+
+	numIn := len(channels[0])
+	numOut := int(math.Round(float64(numIn) / speed))
+
+	// pad the input so that every segment we read is in range
+	pad := 2*frameLen + tolerance
+	mono := padBoth(mixDown(channels), pad)
+	padded := make([][]float64, len(channels))
+	for c := range channels {
+		padded[c] = padBoth(channels[c], pad)
+	}
+
+	// the output starts one hop early so that the first samples receive
+	// contributions from two overlapping windows like all of the others
+	out := make([][]float64, len(channels))
+	for c := range out {
+		out[c] = make([]float64, numOut+frameLen+synthesisHop)
+	}
+
+	// prev is the (padded) input position of the previously copied segment
+	prev := 0
+	for k := 0; (k-1)*synthesisHop < numOut; k++ {
+		pos := pad + int(math.Round(float64((k-1)*synthesisHop)*speed))
+		if k > 0 {
+			pos = bestMatch(mono, prev+synthesisHop, pos, tolerance, frameLen)
+		}
+		for c := range out {
+			for i := 0; i < frameLen; i++ {
+				out[c][k*synthesisHop+i] += padded[c][pos+i] * window[i]
+			}
+		}
+		prev = pos
+	}
+
+	for c := range out {
+		out[c] = out[c][synthesisHop : synthesisHop+numOut]
+	}
+	return out
+}
+
+// bestMatch searches within `tolerance` samples of `pos` for the segment
+// that is most similar (by normalised cross-correlation) to the segment at
+// `target`, returning its position.
+func bestMatch(x []float64, target int, pos int, tolerance int, frameLen int) int {
+	best, bestScore := pos, math.Inf(-1)
+	for candidate := pos - tolerance; candidate <= pos+tolerance; candidate++ {
+		if candidate < 0 || candidate+frameLen > len(x) {
+			continue
+		}
+		dot, energy := 0.0, 1e-12
+		for i := 0; i < frameLen; i += stretchCorrelationStride {
+			dot += x[candidate+i] * x[target+i]
+			energy += x[candidate+i] * x[candidate+i]
+		}
+		if score := dot / math.Sqrt(energy); score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+// padBoth returns a copy of `x` with `n` zeros added on both sides.
+func padBoth(x []float64, n int) []float64 {
+	out := make([]float64, len(x)+2*n)
+	copy(out[n:], x)
+	return out
+}
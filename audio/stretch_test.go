@@ -0,0 +1,79 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// frequencyOf estimates the frequency of a pure tone by counting upward zero
+// crossings.
+func frequencyOf(s []float64, sampleRate float64) float64 {
+	crossings := 0
+	for i := 1; i < len(s); i++ {
+		if s[i-1] < 0 && s[i] >= 0 {
+			crossings++
+		}
+	}
+	return float64(crossings) * sampleRate / float64(len(s))
+}
+
+// Stretching changes the duration but not the frequency of a tone, and keeps
+// its level steady (no dips where segments are joined).
+func TestTimeStretch(t *testing.T) {
+	f := testutils.NewFile(16000, testutils.Sine(220, 0.5, 16000, 16000))
+	for _, speed := range []float64{0.5, 0.75, 1.5, 2} {
+		s, err := f.TimeStretch(speed)
+		require.Nil(t, err)
+		require.InDelta(t, 1/speed, s.Duration(), 1e-3, "speed %v", speed)
+
+		out := s.AudioData.Samples()[0]
+		trimmed := out[800 : len(out)-800]
+		require.InDelta(t, 220, frequencyOf(trimmed, 16000), 3, "speed %v", speed)
+		for i := 0; i+800 <= len(trimmed); i += 800 {
+			require.InDelta(t, 0.5/math.Sqrt2, rmsOf(trimmed[i:i+800]), 0.03, "speed %v", speed)
+		}
+	}
+
+	// the original is untouched
+	require.Equal(t, 1.0, f.Duration())
+}
+
+// Channels are stretched together.
+func TestTimeStretchStereo(t *testing.T) {
+	tone := testutils.Sine(300, 0.5, 16000, 16000)
+	f := testutils.NewFile(16000, tone, tone)
+	s, err := f.TimeStretch(0.8)
+	require.Nil(t, err)
+	require.Equal(t, s.AudioData.Samples()[0], s.AudioData.Samples()[1])
+}
+
+func TestTimeStretchInvalidSpeed(t *testing.T) {
+	f := testutils.NewFile(16000, testutils.Sine(220, 0.5, 16000, 1600))
+	for _, speed := range []float64{0, -1, 0.1, 5, math.NaN()} {
+		_, err := f.TimeStretch(speed)
+		require.IsType(t, errorType, err)
+		require.Equal(t, errors.AudioFileInvalidSpeed, err.(*errors.Error).Code)
+	}
+}
+
+// Shifting up an octave doubles the frequency without changing the duration.
+func TestPitchShift(t *testing.T) {
+	f := testutils.NewFile(16000, testutils.Sine(220, 0.5, 16000, 16000))
+	for _, semitones := range []float64{12, -12, 7} {
+		s, err := f.PitchShift(semitones)
+		require.Nil(t, err)
+		require.Equal(t, f.Duration(), s.Duration())
+
+		out := s.AudioData.Samples()[0]
+		expected := 220 * math.Pow(2, semitones/12)
+		require.InDelta(t, expected, frequencyOf(out[800:len(out)-800], 16000), expected*0.02, "semitones %v", semitones)
+	}
+
+	_, err := f.PitchShift(audio.MaxPitchShift + 1)
+	require.NotNil(t, err)
+}
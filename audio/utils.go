@@ -1,73 +1,81 @@
 package audio
 
 import (
-	"encoding/binary"
+	"io"
 	"math"
 
 	"github.com/gordonklaus/portaudio"
 )
 
-// rms calculates the root-mean-square of a sequence of audio data. For
-// now, it assumes that the data is in 16-bit mono samples. Thus, the passed
-// value for `sampleSize` MUST be 2. This will change once we figure out
-// how to read a variable size of data during runtime.
-func rms(sampleSize int, audioData []byte) float64 {
+// rms calculates the root-mean-square of a sequence of samples. It returns 0
+// for an empty sequence.
+func rms(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
 	sum := 0.0
-	for i := 0; i < len(audioData)-1; i += sampleSize {
-		// had to hard code to Uint16 or else it tries to 8 bytes for Uint64
-		// note that sampleSize MUST be 2 for this to work
-		val := binary.LittleEndian.Uint16(audioData[i:(i + sampleSize)])
-		sum += float64(val * val)
+	for _, v := range samples {
+		sum += v * v
 	}
-
-	return math.Sqrt(sum / (float64(len(audioData) / sampleSize)))
+	return math.Sqrt(sum / float64(len(samples)))
 }
 
 // isSilent determines whether an audio slice is silent or not
 func isSilent(audio []int16) bool {
-	var max int16 = audio[0]
-	for _, value := range audio {
+	return isSilentFrame(int16ToFloat(audio), DefaultSilenceThreshold)
+}
+
+// isSilentFrame determines whether a frame of floating point samples is
+// silent, i.e. its highest sample is below the threshold (relative to full
+// scale). This is the voice activity detection used by the recording
+// framework, so it is shared with anything else that needs to find speech.
+func isSilentFrame(frame []float64, threshold float64) bool {
+	if len(frame) == 0 {
+		return true
+	}
+	max := frame[0]
+	for _, value := range frame {
 		if value > max {
 			max = value
 		}
 	}
-	return max < SilentThresh
+	return max < threshold
 }
 
-// recordResponse is a response emitted on the channel returns from the
-// record function.
-type recordResponse struct {
-	// Data contains the raw data converted from the n-bit sample that
-	// is read from PortAudio
-	Data []byte
-	// Samples contains the raw samples read from PortAudio (do not use --
-	// this is overwritten in every message sent on the channel. Its use
-	// is purely internal)
-	Samples []int16
-	// Error if an error occurred
-	Error error
+// processSamples runs the captured samples through the given processors. If
+// there are no processors, the samples are returned unchanged.
+func processSamples(samples []int16, processors []Processor) []int16 {
+	if len(processors) == 0 {
+		return samples
+	}
+	return floatToInt16(process(int16ToFloat(samples), processors))
+}
+
+// int16ToBytes converts 16-bit samples to little-endian PCM data (you can
+// think of it as reinterpret_cast<char*>(int16array)).
+func int16ToBytes(samples []int16) []byte {
+	data := make([]byte, 2*len(samples))
+	for i, j := 0, 0; i < len(samples); i, j = i+1, j+2 {
+		data[j] = byte(samples[i] & 0xFF)
+		data[j+1] = byte((samples[i] >> 8) & 0xFF)
+	}
+	return data
 }
 
 // record accesses the underlying audio hardware and reads data from it
-// based on the given parameters. It returns a channel of slices which is
-// closed when the recording session has finished. Each slice is raw WAV
-// data, converted from 16-bit samples to an equivalent byte array (each
-// sample is explicitly split into two bytes, but is still the same data --
-// you can think of data as reinterpret_cast<char*>(int16array)
-func record(length float64, silenceLen float64) chan *recordResponse {
-	// we'll send the data in 2048-byte sized arrays. We'll allow buffering
-	// up to 1000 of these, so up to 2MB of buffering. This is so that the
-	// user doesn't cause stuttering audio if they can't consume the data fast
-	// enough.
-	ch := make(chan *recordResponse, 1000)
-
-	// internal processing channel, works the same way as `ch`
-	prch := make(chan *recordResponse, 1000)
+// based on the given parameters. It returns a ring buffer that receives the
+// recorded (and processed) 16-bit samples as they are captured, and which is
+// closed when the recording session has finished. If an error occurs, the
+// buffer is closed with that error. Closing the buffer from the reading side
+// stops the recording.
+func record(params *RecordingParams) *RingBuffer {
+	buffer := NewRingBuffer(params.BufferSize, params.Overflow)
+	events := &eventEmitter{onEvent: params.OnEvent, buffer: buffer}
 
 	// Goroutine for reading data from portaudio
 	go func() {
-		// close the channel when we're done
-		defer close(prch)
+		var err error
+		defer func() { finishCapture(buffer, events, err) }()
 
 		// initialize the underlying APIs for audio transmission
 		portaudio.Initialize()
@@ -77,91 +85,110 @@ func record(length float64, silenceLen float64) chan *recordResponse {
 		buf := make([]int16, BufSize)
 		stream, err := portaudio.OpenDefaultStream(NumChannels, 0, SampleRate, BufSize, buf)
 		if err != nil {
-			prch <- &recordResponse{nil, nil, err}
 			return
 		}
 
 		defer stream.Close()
 		defer stream.Stop()
-		if err := stream.Start(); err != nil {
-			prch <- &recordResponse{nil, nil, err}
+		if err = stream.Start(); err != nil {
 			return
 		}
+		for _, p := range params.Processors {
+			if s, ok := p.(syncer); ok {
+				s.Sync()
+			}
+		}
 
-		// discard silence at the beginning of the recording. Why waste time with it?
-		// however, to avoid an abrupt "chopping", we want to keep some amount of silence
+		err = capture(params, stream.Read, buf, buffer, events)
+	}()
 
-		silenceBuf := make([]int16, 0)
-		maxLen := 16 * BufSize
+	return buffer
+}
 
-		for {
-			stream.Read()
-			// keep up to maxLen previous bytes
-			if len(silenceBuf) > maxLen {
-				silenceBuf = append(silenceBuf[:maxLen], buf...)
-			} else {
-				silenceBuf = append(silenceBuf, buf...)
-			}
-			// check silence here so that we don't have a gap of size BufSize from the audio stream
-			if !isSilent(buf) {
-				break
-			}
-		}
+// capture runs a recording session: it calls `read` to fill `buf` with each
+// buffer of audio from the device, processes it and writes it to `buffer`
+// until the session is over, emitting events as it goes. It returns any
+// error from the device or the buffer.
+func capture(params *RecordingParams, read func() error, buf []int16, buffer *RingBuffer, events *eventEmitter) error {
+	length, silenceLen := params.Length, params.SilenceLen
 
-		// send the recorded silence over to the processing function
-		prch <- &recordResponse{nil, silenceBuf, nil}
+	// discard silence at the beginning of the recording. Why waste time with it?
+	// however, to avoid an abrupt "chopping", we want to keep some amount of silence
 
-		// read data until the specified amount of silence or until the specified amount of length
-		dataLen := 0
-		silentFor := 0.0
-		for {
-			if err := stream.Read(); err != nil {
-				prch <- &recordResponse{nil, nil, err}
-				return
-			}
+	silenceBuf := make([]int16, 0)
+	maxLen := 16 * BufSize
 
-			dataLen += BufSize
-			prch <- &recordResponse{nil, buf, nil}
+	for {
+		// nothing is written until speech starts, so notice here if the
+		// reader has stopped the recording
+		if buffer.isClosed() {
+			return nil
+		}
+		if err := read(); err != nil {
+			return err
+		}
+		samples := processSamples(buf, params.Processors)
+		events.frame(samples)
+		// keep up to maxLen previous bytes
+		if len(silenceBuf) > maxLen {
+			silenceBuf = append(silenceBuf[:maxLen], samples...)
+		} else {
+			silenceBuf = append(silenceBuf, samples...)
+		}
+		// check silence here so that we don't have a gap of size BufSize from the audio stream
+		if !isSilent(samples) {
+			events.emit(EventSpeechStarted, nil)
+			break
+		}
+	}
 
-			if isSilent(buf) {
-				silentFor += float64(BufSize) / SampleRate
-			} else {
-				silentFor = 0.0
-			}
+	// send the recorded silence over to the reader
+	if err := buffer.Write(silenceBuf); err != nil {
+		return err
+	}
 
-			if length == 0 && silentFor > silenceLen {
-				break
-			}
+	// read data until the specified amount of silence or until the specified amount of length
+	dataLen := 0
+	silentFor := 0.0
+	for {
+		if err := read(); err != nil {
+			return err
+		}
 
-			if length > 0 && dataLen > int(length*SampleRate) {
-				break
-			}
+		dataLen += BufSize
+		samples := processSamples(buf, params.Processors)
+		events.frame(samples)
+		if err := buffer.Write(samples); err != nil {
+			return err
 		}
-	}()
 
-	// Goroutine for converting audio and sending to the user
-	go func() {
-		// close the channel when we're done receiving all audio samples
-		defer close(ch)
-		for res := range prch {
-			// check if port audio threw an error
-			if res.Error != nil {
-				ch <- res
-				return
-			}
+		if isSilent(samples) {
+			silentFor += float64(BufSize) / SampleRate
+		} else {
+			silentFor = 0.0
+		}
 
-			// convert each element from a 16-bit value to two 8-bit values that
-			// are equivalent in little-endian form.
-			d := res.Samples
-			res.Data = make([]byte, 2*len(d))
-			for i, j := 0, 0; i < len(d); i, j = i+1, j+2 {
-				res.Data[j] = byte(d[i] & 0xFF)
-				res.Data[j+1] = byte((d[i] >> 8) & 0xFF)
-			}
+		if length == 0 && silentFor > silenceLen {
+			events.emit(EventSpeechEnded, nil)
+			return nil
+		}
 
-			ch <- res
+		if length > 0 && dataLen > int(length*SampleRate) {
+			events.emit(EventTimeout, nil)
+			return nil
 		}
-	}()
+	}
+}
 
-	return ch
+// finishCapture closes the buffer once a recording session is over, passing
+// on any error to the reader and reporting it as an event. If the reader
+// closed the buffer, there's nobody to tell.
+func finishCapture(buffer *RingBuffer, events *eventEmitter, err error) {
+	if err == io.ErrClosedPipe {
+		err = nil
+	}
+	if err != nil {
+		events.emit(EventError, err)
+	}
+	buffer.Close(err)
 }
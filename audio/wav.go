@@ -4,7 +4,6 @@ import (
 	"encoding/binary"
 	"io"
 	"io/ioutil"
-	"math"
 
 	"github.com/auroraapi/aurora-go/errors"
 )
@@ -167,15 +166,14 @@ func (w *WAV) TrimSilent(threshold float64, padding float64) {
 	// number of bytes to examine in each step
 	step := 1024
 
-	// get max amplitude
-	maxPossibleAmp := math.Exp2(float64(w.BitsPerSample)) / 2.0
-	// silenceThresh is a percentage of the maximum wave height
-	silenceThresh := threshold * maxPossibleAmp
+	// samples are normalised to full scale, so the threshold can be used as is
+	silenceThresh := threshold
+	bytesPerSample := int(w.BitsPerSample / 8)
 
 	// Trimming the beginning
 	N1 := 0
 	for N1 < len(w.audioData) {
-		sampleRMS := rms(sampleSize, w.audioData[N1:N1+(sampleSize*step)])
+		sampleRMS := rms(decodeSamples(w.audioData[N1:N1+(sampleSize*step)], bytesPerSample))
 		if sampleRMS > silenceThresh {
 			break
 		}
@@ -185,7 +183,7 @@ func (w *WAV) TrimSilent(threshold float64, padding float64) {
 	// Trimming the end
 	N2 := len(w.audioData)
 	for N2 >= 0 {
-		sampleRMS := rms(sampleSize, w.audioData[N2-(sampleSize*step):N2])
+		sampleRMS := rms(decodeSamples(w.audioData[N2-(sampleSize*step):N2], bytesPerSample))
 		if sampleRMS > silenceThresh {
 			break
 		}
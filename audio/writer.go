@@ -0,0 +1,316 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+// DefaultFlushInterval is the default amount of audio (in seconds) written
+// between updates of a file's header.
+const DefaultFlushInterval = 1.0
+
+// wavHeaderLen is the length of the WAV header written by `WAV.Data`.
+const wavHeaderLen = 44
+
+// FileFormat is the format of audio files written by a `FileSink`.
+type FileFormat int
+
+const (
+	// FormatWAV writes uncompressed WAV files.
+	FormatWAV FileFormat = iota
+	// FormatFLAC writes losslessly compressed FLAC files (16-bit audio only).
+	FormatFLAC
+)
+
+// Ext returns the file extension (including the dot) of the format.
+func (f FileFormat) Ext() string {
+	if f == FormatFLAC {
+		return ".flac"
+	}
+	return ".wav"
+}
+
+// WAVWriter writes a WAV file as the audio is received, rather than holding
+// it all in memory. The sizes in the header are updated whenever it is
+// flushed, so the file is always valid up to the last flush.
+type WAVWriter struct {
+	w       io.WriteSeeker
+	dataLen int64
+}
+
+// NewWAVWriter writes a WAV header for audio with the given format to `w` and
+// returns a writer for the audio data.
+func NewWAVWriter(w io.WriteSeeker, sampleRate uint32, numChannels uint16, bitsPerSample uint16) (*WAVWriter, error) {
+	if sampleRate == 0 || numChannels == 0 || bitsPerSample == 0 || bitsPerSample%8 != 0 || bitsPerSample > 32 {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidFormat, fmt.Sprintf("%dHz, %d channel(s), %d bits per sample were requested.", sampleRate, numChannels, bitsPerSample))
+	}
+
+	header := NewWAVFromParams(&WAVParams{
+		NumChannels:   numChannels,
+		SampleRate:    sampleRate,
+		BitsPerSample: bitsPerSample,
+	}).Data()
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &WAVWriter{w: w}, nil
+}
+
+// Write appends raw audio data (in the format given to `NewWAVWriter`).
+func (w *WAVWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.dataLen += int64(n)
+	return n, err
+}
+
+// Flush updates the header with the length of the audio written so far, and
+// syncs it to disk if the underlying writer is a file.
+func (w *WAVWriter) Flush() error {
+	sizes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sizes, uint32(w.dataLen+wavHeaderLen-8))
+	if err := writeAt(w.w, sizes, 4); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(sizes, uint32(w.dataLen))
+	if err := writeAt(w.w, sizes, 40); err != nil {
+		return err
+	}
+	return syncFile(w.w)
+}
+
+// Close flushes the writer. It doesn't close the underlying writer.
+func (w *WAVWriter) Close() error {
+	return w.Flush()
+}
+
+// Size returns the number of bytes written so far.
+func (w *WAVWriter) Size() int64 {
+	return wavHeaderLen + w.dataLen
+}
+
+// writeAt overwrites the bytes at `offset`, then returns to the end of the
+// file.
+func writeAt(w io.WriteSeeker, p []byte, offset int64) error {
+	if _, err := w.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.Write(p); err != nil {
+		return err
+	}
+	_, err := w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// syncFile commits the written data to disk if `w` is a file.
+func syncFile(w io.Writer) error {
+	if f, ok := w.(*os.File); ok {
+		return f.Sync()
+	}
+	return nil
+}
+
+// audioWriter is implemented by `WAVWriter` and `FLACWriter`.
+type audioWriter interface {
+	io.Writer
+	Flush() error
+	Close() error
+	Size() int64
+}
+
+// FileSinkParams configures how a `FileSink` writes audio to disk.
+type FileSinkParams struct {
+	// Path is the file to write to. The format's extension is added if it
+	// has none. If the output is rotated, a sequence number is added before
+	// the extension, e.g. "dictation-001.wav".
+	Path string
+	// Format is the format of the files written.
+	Format FileFormat
+	// FlushInterval is how much audio (in seconds) is written between
+	// updates of the header, which bounds how much is lost if the program
+	// crashes.
+	FlushInterval float64
+	// RotateDuration, if positive, starts a new file after this many seconds
+	// of audio.
+	RotateDuration float64
+	// RotateSize, if positive, starts a new file once the current one
+	// reaches this many bytes.
+	RotateSize int64
+}
+
+// NewFileSinkParams creates the default set of FileSinkParams, which write a
+// single WAV file. You should call this function to get the default and then
+// set the path and replace the ones you want to customize.
+func NewFileSinkParams() *FileSinkParams {
+	return &FileSinkParams{
+		Format:        FormatWAV,
+		FlushInterval: DefaultFlushInterval,
+	}
+}
+
+// FileSink writes a stream of 16-bit audio to one or more files on disk
+// without holding it in memory. The header of the current file is updated
+// regularly, so each file can be read even if the program crashes before the
+// sink is closed.
+type FileSink struct {
+	params      *FileSinkParams
+	sampleRate  uint32
+	numChannels uint16
+
+	file   *os.File
+	writer audioWriter
+	files  []string
+	// samples is the number of samples (per channel) written to the current
+	// file, and unflushed the number since its header was last updated
+	samples   int
+	unflushed int
+	closed    bool
+}
+
+// NewFileSink creates the first file for a sink that writes audio with the
+// given format. Pass `nil` for the params to use the defaults, though a path
+// must be given.
+func NewFileSink(params *FileSinkParams, sampleRate uint32, numChannels uint16) (*FileSink, error) {
+	if params == nil {
+		params = NewFileSinkParams()
+	}
+	// don't change the caller's params when filling in defaults
+	p := *params
+	params = &p
+	if params.FlushInterval <= 0 {
+		params.FlushInterval = DefaultFlushInterval
+	}
+	if params.RotateSize < 0 {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidRotation, fmt.Sprintf("A rotation size of %d bytes was requested.", params.RotateSize))
+	}
+	if params.RotateDuration > 0 && sampleRate > 0 && int(params.RotateDuration*float64(sampleRate)) < 1 {
+		return nil, errors.NewFromErrorCodeInfo(errors.AudioFileInvalidRotation, fmt.Sprintf("A rotation duration of %vs was requested, which is less than one sample at %dHz.", params.RotateDuration, sampleRate))
+	}
+
+	s := &FileSink{params: params, sampleRate: sampleRate, numChannels: numChannels}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write appends interleaved samples to the current file, starting new files
+// as required by the rotation settings. The samples must be a whole number of
+// frames (one sample per channel). Writing to a closed sink returns
+// `os.ErrClosed`.
+func (s *FileSink) Write(samples []int16) error {
+	if s.closed {
+		return os.ErrClosed
+	}
+	channels := int(s.numChannels)
+	if len(samples)%channels != 0 {
+		return errors.NewFromErrorCodeInfo(errors.AudioFileInvalidFormat, fmt.Sprintf("Wrote %d samples, which isn't a whole number of %d channel frames.", len(samples), channels))
+	}
+	for len(samples) > 0 {
+		if s.writer == nil {
+			if err := s.open(); err != nil {
+				return err
+			}
+		}
+
+		// write up to the end of the current file's duration
+		n := len(samples) / channels
+		if s.params.RotateDuration > 0 {
+			remaining := int(s.params.RotateDuration*float64(s.sampleRate)) - s.samples
+			if remaining <= 0 {
+				if err := s.rotate(); err != nil {
+					return err
+				}
+				continue
+			}
+			if remaining < n {
+				n = remaining
+			}
+		}
+
+		if _, err := s.writer.Write(int16ToBytes(samples[:n*channels])); err != nil {
+			return err
+		}
+		samples = samples[n*channels:]
+		s.samples += n
+		s.unflushed += n
+
+		if s.unflushed >= int(s.params.FlushInterval*float64(s.sampleRate)) {
+			if err := s.writer.Flush(); err != nil {
+				return err
+			}
+			s.unflushed = 0
+		}
+		if s.params.RotateSize > 0 && s.writer.Size() >= s.params.RotateSize {
+			if err := s.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close completes and closes the current file. No more audio can be written
+// afterwards.
+func (s *FileSink) Close() error {
+	s.closed = true
+	if s.writer == nil {
+		return nil
+	}
+	return s.rotate()
+}
+
+// Files returns the paths of the files written so far, in order.
+func (s *FileSink) Files() []string {
+	return append([]string(nil), s.files...)
+}
+
+// open creates the next file.
+func (s *FileSink) open() error {
+	path, ext := s.params.Path, filepath.Ext(s.params.Path)
+	if ext == "" {
+		ext = s.params.Format.Ext()
+		path += ext
+	}
+	if s.params.RotateDuration > 0 || s.params.RotateSize > 0 {
+		path = fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(path, ext), len(s.files)+1, ext)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	var writer audioWriter
+	if s.params.Format == FormatFLAC {
+		writer, err = NewFLACWriter(file, s.sampleRate, s.numChannels, 16)
+	} else {
+		writer, err = NewWAVWriter(file, s.sampleRate, s.numChannels, 16)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+
+	s.file, s.writer = file, writer
+	s.files = append(s.files, path)
+	s.samples, s.unflushed = 0, 0
+	return nil
+}
+
+// rotate completes and closes the current file. The next one is only created
+// when there is more audio to write to it.
+func (s *FileSink) rotate() error {
+	err := s.writer.Close()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file, s.writer = nil, nil
+	return err
+}
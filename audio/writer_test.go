@@ -0,0 +1,235 @@
+package audio_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// tone returns `seconds` of 16-bit mono audio at 16KHz.
+func tone(seconds float64) []int16 {
+	s := testutils.Sine(440, 0.5, 16000, int(seconds*16000))
+	out := make([]int16, len(s))
+	for i, v := range s {
+		out[i] = int16(v * 32767)
+	}
+	return out
+}
+
+// writeInBuffers writes the samples to the sink in pieces of `BufSize`, like
+// a recording.
+func writeInBuffers(t *testing.T, sink *audio.FileSink, samples []int16) {
+	for i := 0; i < len(samples); i += audio.BufSize {
+		end := i + audio.BufSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+		require.Nil(t, sink.Write(samples[i:end]))
+	}
+}
+
+func TestWAVWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aurora")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.wav")
+	file, err := os.Create(path)
+	require.Nil(t, err)
+	defer file.Close()
+
+	w, err := audio.NewWAVWriter(file, 16000, 1, 16)
+	require.Nil(t, err)
+	data := pcm(tone(0.5))
+	_, err = w.Write(data)
+	require.Nil(t, err)
+	require.Nil(t, w.Flush())
+
+	// the flushed file is valid while more is being written
+	_, err = w.Write(data)
+	require.Nil(t, err)
+	f, err := audio.NewFileFromFileName(path)
+	require.Nil(t, err)
+	require.Equal(t, uint32(16000), f.AudioData.SampleRate)
+	require.InDelta(t, 1.0, f.Duration(), 1e-9)
+
+	require.Nil(t, w.Close())
+	written, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, int64(len(written)), w.Size())
+	f, err = audio.NewFileFromBytes(written)
+	require.Nil(t, err)
+	require.Equal(t, append(data, data...), f.AudioData.AudioData())
+
+	_, err = audio.NewWAVWriter(file, 16000, 1, 12)
+	require.NotNil(t, err)
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aurora")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	params := audio.NewFileSinkParams()
+	params.Path = filepath.Join(dir, "dictation")
+	params.FlushInterval = 0.25
+	sink, err := audio.NewFileSink(params, 16000, 1)
+	require.Nil(t, err)
+
+	writeInBuffers(t, sink, tone(1))
+	// readable before the sink is closed, up to the last flush
+	f, err := audio.NewFileFromFileName(params.Path + ".wav")
+	require.Nil(t, err)
+	require.True(t, f.Duration() >= 0.75)
+
+	require.Nil(t, sink.Close())
+	require.Equal(t, []string{params.Path + ".wav"}, sink.Files())
+	f, err = audio.NewFileFromFileName(params.Path + ".wav")
+	require.Nil(t, err)
+	require.InDelta(t, 1.0, f.Duration(), 1e-9)
+
+	// the file isn't reopened (and truncated) by writes after closing
+	require.Equal(t, os.ErrClosed, sink.Write(tone(1)))
+	require.Nil(t, sink.Close())
+	f, err = audio.NewFileFromFileName(params.Path + ".wav")
+	require.Nil(t, err)
+	require.InDelta(t, 1.0, f.Duration(), 1e-9)
+	require.Equal(t, []string{params.Path + ".wav"}, sink.Files())
+}
+
+// Samples that don't fill every channel of the last frame are rejected
+// without touching the files.
+func TestFileSinkPartialFrame(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aurora")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, rotate := range []float64{0, 1} {
+		params := audio.NewFileSinkParams()
+		params.Path = filepath.Join(dir, fmt.Sprintf("stereo%v", rotate))
+		params.RotateDuration = rotate
+		sink, err := audio.NewFileSink(params, 16000, 2)
+		require.Nil(t, err)
+
+		err = sink.Write([]int16{1, 2, 3})
+		require.NotNil(t, err)
+		require.Equal(t, errors.AudioFileInvalidFormat, err.(*errors.Error).Code)
+		require.Len(t, sink.Files(), 1)
+
+		require.Nil(t, sink.Write([]int16{1, 2, 3, 4}))
+		require.Nil(t, sink.Close())
+		require.Len(t, sink.Files(), 1)
+		f, err := audio.NewFileFromFileName(sink.Files()[0])
+		require.Nil(t, err)
+		require.Equal(t, pcm([]int16{1, 2, 3, 4}), f.AudioData.AudioData())
+	}
+}
+
+func TestFileSinkInvalidParams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aurora")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	params := audio.NewFileSinkParams()
+	params.Path = filepath.Join(dir, "dictation")
+	params.RotateDuration = 1e-5
+	_, err = audio.NewFileSink(params, 16000, 1)
+	require.NotNil(t, err)
+	require.Equal(t, errors.AudioFileInvalidRotation, err.(*errors.Error).Code)
+
+	params.RotateDuration = 0
+	params.RotateSize = -1
+	_, err = audio.NewFileSink(params, 16000, 1)
+	require.NotNil(t, err)
+	require.Equal(t, errors.AudioFileInvalidRotation, err.(*errors.Error).Code)
+
+	// defaults are filled in without changing the caller's params
+	params.RotateSize = 0
+	params.FlushInterval = 0
+	sink, err := audio.NewFileSink(params, 16000, 1)
+	require.Nil(t, err)
+	require.Equal(t, 0.0, params.FlushInterval)
+	require.Nil(t, sink.Close())
+}
+
+func TestFileSinkRotateDuration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aurora")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	params := audio.NewFileSinkParams()
+	params.Path = filepath.Join(dir, "dictation.wav")
+	params.RotateDuration = 1
+	sink, err := audio.NewFileSink(params, 16000, 1)
+	require.Nil(t, err)
+	samples := tone(2.5)
+	writeInBuffers(t, sink, samples)
+	require.Nil(t, sink.Close())
+
+	files := sink.Files()
+	require.Equal(t, []string{
+		filepath.Join(dir, "dictation-001.wav"),
+		filepath.Join(dir, "dictation-002.wav"),
+		filepath.Join(dir, "dictation-003.wav"),
+	}, files)
+
+	// the files split the audio exactly
+	joined := make([]byte, 0)
+	for i, expected := range []float64{1, 1, 0.5} {
+		f, err := audio.NewFileFromFileName(files[i])
+		require.Nil(t, err)
+		require.InDelta(t, expected, f.Duration(), 1e-9)
+		joined = append(joined, f.AudioData.AudioData()...)
+	}
+	require.Equal(t, pcm(samples), joined)
+}
+
+func TestFileSinkRotateSizeFLAC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aurora")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	params := audio.NewFileSinkParams()
+	params.Path = filepath.Join(dir, "dictation.flac")
+	params.Format = audio.FormatFLAC
+	params.RotateSize = 400000
+	sink, err := audio.NewFileSink(params, 16000, 1)
+	require.Nil(t, err)
+	// a tone that compresses well, so that the first file is long enough for
+	// frame numbers that take more than one byte, then noise that doesn't
+	samples := tone(40)
+	for _, v := range testutils.Noise(0.2, 16000*20, 1) {
+		samples = append(samples, int16(v*32767))
+	}
+	writeInBuffers(t, sink, samples)
+	require.Nil(t, sink.Close())
+
+	files := sink.Files()
+	require.True(t, len(files) > 1)
+	decoded := make([]int16, 0, len(samples))
+	for i, name := range files {
+		require.Equal(t, filepath.Join(dir, fmt.Sprintf("dictation-%03d.flac", i+1)), name)
+		data, err := ioutil.ReadFile(name)
+		require.Nil(t, err)
+		// every file but the last reached the limit by at most a frame
+		if i < len(files)-1 {
+			require.True(t, len(data) >= 400000 && len(data) < 400000+10000)
+		}
+		stream, err := decodeFLAC(data)
+		require.Nil(t, err)
+		if i == 0 {
+			require.True(t, len(stream.samples[0]) > 128*4096)
+		}
+		for _, v := range stream.samples[0] {
+			decoded = append(decoded, int16(v))
+		}
+	}
+	require.Equal(t, samples, decoded)
+}
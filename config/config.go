@@ -4,6 +4,7 @@ package config
 
 import (
 	"github.com/auroraapi/aurora-go/api/backend"
+	"github.com/auroraapi/aurora-go/errors"
 )
 
 // Config configures the parameters that this SDK will use to operate
@@ -19,6 +20,15 @@ type Config struct {
 
 	// Backend to use for requests (configurable for testing purposes)
 	Backend backend.Backend
+
+	// QualityCheck, if set, assesses the quality of audio before it is sent
+	// for speech to text and rejects unusable audio with an error, rather than
+	// paying for it to be transcribed (optional)
+	QualityCheck *QualityParams
+
+	// QualityWarning, if set, is called with the problems found by
+	// QualityCheck instead of rejecting the audio, e.g. to log them (optional)
+	QualityWarning func(problems []*errors.Error)
 }
 
 // GetCredentials converts the client's credentials into a struct
@@ -0,0 +1,51 @@
+package config
+
+const (
+	// DefaultQualityMinDuration is the default length (in seconds) of the
+	// shortest audio that is worth transcribing.
+	DefaultQualityMinDuration = 0.25
+	// DefaultQualityMaxSilenceRatio is the default highest fraction of the
+	// audio that may be silent.
+	DefaultQualityMaxSilenceRatio = 0.95
+	// DefaultQualityMaxClippingRatio is the default highest fraction of
+	// samples that may be clipped.
+	DefaultQualityMaxClippingRatio = 0.01
+	// DefaultQualityMinSNR is the default lowest estimated signal to noise
+	// ratio (in dB).
+	DefaultQualityMinSNR = 6.0
+	// DefaultQualityMinSampleRate is the default lowest sample rate (in Hz).
+	// Anything lower than telephone quality loses too much of the speech.
+	DefaultQualityMinSampleRate = 8000
+)
+
+// QualityParams are the requirements that audio must meet to pass a quality
+// check (see `audio.QualityReport.Check`). They are defined here, rather than
+// in the audio package, so that the config doesn't depend on the audio
+// hardware libraries.
+type QualityParams struct {
+	// MinDuration is the length (in seconds) of the shortest acceptable audio.
+	MinDuration float64
+	// MaxSilenceRatio is the highest acceptable fraction (between 0 and 1)
+	// of the audio that is silent.
+	MaxSilenceRatio float64
+	// MaxClippingRatio is the highest acceptable fraction (between 0 and 1)
+	// of samples that are clipped.
+	MaxClippingRatio float64
+	// MinSNR is the lowest acceptable estimated signal to noise ratio (in dB).
+	MinSNR float64
+	// MinSampleRate is the lowest acceptable sample rate (in Hz).
+	MinSampleRate uint32
+}
+
+// NewQualityParams creates the default set of QualityParams. You should
+// call this function to get the default and then replace the ones you want
+// to customize.
+func NewQualityParams() *QualityParams {
+	return &QualityParams{
+		MinDuration:      DefaultQualityMinDuration,
+		MaxSilenceRatio:  DefaultQualityMaxSilenceRatio,
+		MaxClippingRatio: DefaultQualityMaxClippingRatio,
+		MinSNR:           DefaultQualityMinSNR,
+		MinSampleRate:    DefaultQualityMinSampleRate,
+	}
+}
@@ -0,0 +1,262 @@
+package aurora
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/auroraapi/aurora-go/api"
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+// entityTag is the struct tag that maps a field to an entity.
+const entityTag = "entity"
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	locationType        = reflect.TypeOf(api.Location{})
+	entityType          = reflect.TypeOf(&api.InterpretEntity{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// DecodeEntities sets the fields of the struct pointed to by `v` to the
+// entities they are tagged with, converting each one to the type of its
+// field. For example:
+//
+//	type Timer struct {
+//		Length time.Duration `entity:"duration,required"`
+//		Label  string        `entity:"label"`
+//		Sound  Sound         `entity:"sound,oneof=bell|chime"`
+//	}
+//
+//	var timer Timer
+//	err := interpret.DecodeEntities(&timer)
+//
+// Fields without a tag (or tagged "-") are left alone, as are the fields of
+// entities that weren't found, unless they are "required". Fields can be:
+//   - strings, or types based on strings (such as enums, whose allowed
+//     values can be listed with "oneof"), or types that implement
+//     `encoding.TextUnmarshaler`;
+//   - integers, floats and booleans;
+//   - `time.Time` for dates and times, and `time.Duration`;
+//   - `api.Location` for locations;
+//   - `*api.InterpretEntity` for all of the entity's details;
+//   - a pointer to any of these, which is only set if the entity was found.
+//
+// If any required entities are missing, it returns an
+// `InterpretMissingEntity` error listing all of them, so that the user can
+// be asked for them. An entity that can't be converted is reported as an
+// `InterpretInvalidEntity` error. A `nil` Interpret, such as the one
+// `NewInterpret` returns for a `nil` response, has no entities at all.
+func (i *Interpret) DecodeEntities(v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return errors.NewFromErrorCodeInfo(errors.InterpretInvalidTarget, fmt.Sprintf("Can't decode entities into a %T.", v))
+	}
+	target = target.Elem()
+
+	missing := make([]string, 0)
+	for f := 0; f < target.NumField(); f++ {
+		field := target.Type().Field(f)
+		tag, ok := field.Tag.Lookup(entityTag)
+		if !ok || tag == "-" || field.PkgPath != "" {
+			continue
+		}
+		if !supportedEntityType(field.Type) {
+			return errors.NewFromErrorCodeInfo(errors.InterpretInvalidTarget, fmt.Sprintf("Can't decode entities into %s (%s).", field.Name, field.Type))
+		}
+		name, required, oneOf := parseEntityTag(tag, field.Name)
+
+		entity := i.entityOrText(name)
+		if entity == nil {
+			if required {
+				missing = append(missing, name)
+			}
+			continue
+		}
+		if len(oneOf) > 0 && !contains(oneOf, entity.Value.Text) {
+			return errors.NewFromErrorCodeInfo(errors.InterpretInvalidEntity, fmt.Sprintf("The %s %q isn't one of %s.", name, entity.Value.Text, strings.Join(oneOf, ", ")))
+		}
+		if err := setEntity(target.Field(f), entity); err != nil {
+			return errors.NewFromErrorCodeInfo(errors.InterpretInvalidEntity, fmt.Sprintf("The %s %q can't be decoded into %s (%s): %s", name, entity.Value.Text, field.Name, field.Type, err))
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.NewFromErrorCodeInfo(errors.InterpretMissingEntity, fmt.Sprintf("Missing %s.", strings.Join(missing, ", ")))
+	}
+	return nil
+}
+
+// parseEntityTag splits a tag into the entity name (the field name if it is
+// empty) and its options.
+func parseEntityTag(tag string, fieldName string) (name string, required bool, oneOf []string) {
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = fieldName
+	}
+	for _, option := range parts[1:] {
+		switch {
+		case option == "required":
+			required = true
+		case strings.HasPrefix(option, "oneof="):
+			oneOf = strings.Split(strings.TrimPrefix(option, "oneof="), "|")
+		}
+	}
+	return name, required, oneOf
+}
+
+// entityOrText returns the details of the entity with the given name. If
+// there are no details but the entity is in `Entities`, it returns a text
+// entity with its value.
+func (i *Interpret) entityOrText(name string) *api.InterpretEntity {
+	if i == nil {
+		return nil
+	}
+	if e := i.Entity(name); e != nil {
+		if e.Value == nil {
+			withValue := *e
+			withValue.Value = &api.EntityValue{Text: e.Text}
+			return &withValue
+		}
+		return e
+	}
+	if text, ok := i.Entities[name]; ok {
+		return &api.InterpretEntity{Name: name, Type: api.EntityText, Text: text, Value: &api.EntityValue{Text: text}}
+	}
+	return nil
+}
+
+// supportedEntityType returns whether entities can be decoded into a field
+// of type `t`.
+func supportedEntityType(t reflect.Type) bool {
+	if t == entityType {
+		return true
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || t == durationType || t == locationType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// setEntity converts an entity to the type of a field and sets the field.
+func setEntity(field reflect.Value, entity *api.InterpretEntity) error {
+	t := field.Type()
+	if t == entityType {
+		field.Set(reflect.ValueOf(entity))
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		value := reflect.New(t.Elem())
+		if err := setEntity(value.Elem(), entity); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+	value := entity.Value
+	switch t {
+	case timeType:
+		if !value.Time.IsZero() {
+			field.Set(reflect.ValueOf(value.Time))
+			return nil
+		}
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if parsed, err := time.Parse(layout, value.Text); err == nil {
+				field.Set(reflect.ValueOf(parsed))
+				return nil
+			}
+		}
+		return fmt.Errorf("it isn't a time")
+	case durationType:
+		if entity.Type == api.EntityDuration {
+			field.SetInt(int64(value.Duration()))
+			return nil
+		}
+		d, err := time.ParseDuration(value.Text)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case locationType:
+		if value.Location == nil {
+			return fmt.Errorf("it isn't a location")
+		}
+		field.Set(reflect.ValueOf(*value.Location))
+		return nil
+	}
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value.Text))
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		field.SetString(value.Text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value.Text)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := entityNumber(entity)
+		if err != nil {
+			return err
+		}
+		if n != float64(int64(n)) || field.OverflowInt(int64(n)) {
+			return fmt.Errorf("it isn't a whole number in range")
+		}
+		field.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := entityNumber(entity)
+		if err != nil {
+			return err
+		}
+		if n < 0 || n != float64(uint64(n)) || field.OverflowUint(uint64(n)) {
+			return fmt.Errorf("it isn't a whole number in range")
+		}
+		field.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, err := entityNumber(entity)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	}
+	return nil
+}
+
+// entityNumber returns the value of a number entity, or parses the text of
+// another entity as a number.
+func entityNumber(entity *api.InterpretEntity) (float64, error) {
+	if entity.Type == api.EntityNumber {
+		return entity.Value.Number, nil
+	}
+	return strconv.ParseFloat(entity.Value.Text, 64)
+}
+
+// contains returns whether `s` is one of `values`.
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package aurora_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	aurora "github.com/auroraapi/aurora-go"
+	"github.com/auroraapi/aurora-go/api"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type sound string
+
+type unit int
+
+func (u *unit) UnmarshalText(text []byte) error {
+	*u = unit(strings.Count(string(text), "!"))
+	return nil
+}
+
+type timer struct {
+	Length   time.Duration        `entity:"duration,required"`
+	When     time.Time            `entity:"date"`
+	Where    api.Location         `entity:"location"`
+	Repeats  int                  `entity:"count"`
+	Volume   float64              `entity:"volume"`
+	Sound    sound                `entity:"sound,oneof=bell|chime"`
+	Label    string               `entity:"label"`
+	Unit     unit                 `entity:"unit"`
+	Snooze   *time.Duration       `entity:"snooze"`
+	Detail   *api.InterpretEntity `entity:"location"`
+	Untagged string
+}
+
+func timerInterpret() *aurora.Interpret {
+	return aurora.NewInterpret(&api.InterpretResponse{
+		Intent: "timer",
+		Entities: map[string]string{
+			"duration": "10 minutes",
+			"label":    "pasta",
+			"volume":   "0.5",
+			"unit":     "!!!",
+		},
+		EntityDetails: []*api.InterpretEntity{
+			{Name: "duration", Type: api.EntityDuration, Text: "ten minutes", Value: &api.EntityValue{Text: "10 minutes", Seconds: 600}},
+			{Name: "date", Type: api.EntityDate, Text: "tomorrow", Value: &api.EntityValue{Text: "2026-10-20"}},
+			{Name: "location", Type: api.EntityLocation, Text: "LA", Value: &api.EntityValue{Text: "los angeles", Location: &api.Location{Name: "Los Angeles"}}},
+			{Name: "count", Type: api.EntityNumber, Text: "three", Value: &api.EntityValue{Text: "3", Number: 3}},
+			{Name: "sound", Type: api.EntityText, Text: "chime", Value: &api.EntityValue{Text: "chime"}},
+		},
+	})
+}
+
+func TestDecodeEntities(t *testing.T) {
+	i := timerInterpret()
+	tm := timer{Untagged: "kept"}
+	require.Nil(t, i.DecodeEntities(&tm))
+
+	require.Equal(t, 10*time.Minute, tm.Length)
+	require.Equal(t, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), tm.When)
+	require.Equal(t, "Los Angeles", tm.Where.Name)
+	require.Equal(t, 3, tm.Repeats)
+	require.Equal(t, 0.5, tm.Volume)
+	require.Equal(t, sound("chime"), tm.Sound)
+	require.Equal(t, "pasta", tm.Label)
+	require.Equal(t, unit(3), tm.Unit)
+	require.Nil(t, tm.Snooze)
+	require.Equal(t, i.Entity("location"), tm.Detail)
+	require.Equal(t, "kept", tm.Untagged)
+
+	var snooze struct {
+		Snooze *time.Duration `entity:"duration"`
+	}
+	require.Nil(t, i.DecodeEntities(&snooze))
+	require.Equal(t, 10*time.Minute, *snooze.Snooze)
+}
+
+// All of the missing required entities are reported, so they can be asked
+// for.
+func TestDecodeEntitiesMissing(t *testing.T) {
+	var booking struct {
+		From  string    `entity:"from,required"`
+		To    string    `entity:"to,required"`
+		Label string    `entity:"label,required"`
+		When  time.Time `entity:"date"`
+	}
+	err := timerInterpret().DecodeEntities(&booking)
+	require.NotNil(t, err)
+	require.Equal(t, errors.InterpretMissingEntity, err.(*errors.Error).Code)
+	require.Equal(t, "Missing from, to.", err.(*errors.Error).Info)
+	require.Equal(t, "pasta", booking.Label)
+}
+
+func TestDecodeEntitiesInvalid(t *testing.T) {
+	i := timerInterpret()
+
+	var number struct {
+		Label int `entity:"label"`
+	}
+	var fraction struct {
+		Volume int `entity:"volume"`
+	}
+	var enum struct {
+		Sound sound `entity:"sound,oneof=bell|buzzer"`
+	}
+	var location struct {
+		Where api.Location `entity:"label"`
+	}
+	for _, v := range []interface{}{&number, &fraction, &enum, &location} {
+		err := i.DecodeEntities(v)
+		require.NotNil(t, err, "%T", v)
+		require.Equal(t, errors.InterpretInvalidEntity, err.(*errors.Error).Code)
+	}
+
+	var unsupported struct {
+		Labels map[string]string `entity:"label"`
+	}
+	for _, v := range []interface{}{timer{}, (*timer)(nil), "label", &unsupported} {
+		err := i.DecodeEntities(v)
+		require.NotNil(t, err, "%T", v)
+		require.Equal(t, errors.InterpretInvalidTarget, err.(*errors.Error).Code)
+	}
+}
+
+// Without a response there are no entities, so only required ones are
+// reported.
+func TestDecodeEntitiesNilInterpret(t *testing.T) {
+	i := aurora.NewInterpret(nil)
+	require.Nil(t, i.Entity("duration"))
+
+	var alarm timer
+	err := i.DecodeEntities(&alarm)
+	require.NotNil(t, err)
+	require.Equal(t, errors.InterpretMissingEntity, err.(*errors.Error).Code)
+	require.Equal(t, "Missing duration.", err.(*errors.Error).Info)
+
+	var optional struct {
+		Label string `entity:"label"`
+	}
+	require.Nil(t, i.DecodeEntities(&optional))
+
+	err = i.DecodeEntities(timer{})
+	require.NotNil(t, err)
+	require.Equal(t, errors.InterpretInvalidTarget, err.(*errors.Error).Code)
+}
@@ -5,18 +5,56 @@ type ErrorCode string
 
 // Define the various error codes possible
 const (
-	SpeechNilAudio = "SpeechNilAudio"
-	WAVCorruptFile = "WAVCorruptFile"
+	SpeechNilAudio                 = "SpeechNilAudio"
+	WAVCorruptFile                 = "WAVCorruptFile"
 	AudioFileOutputStreamNotOpened = "AudioFileOutputStreamNotOpened"
-	AudioFileNotWritableStream = "AudioFileNotWritableStream"
+	AudioFileNotWritableStream     = "AudioFileNotWritableStream"
+	AudioFileInvalidRange          = "AudioFileInvalidRange"
+	AudioFileInvalidFormat         = "AudioFileInvalidFormat"
+	AudioFileInvalidSpeed          = "AudioFileInvalidSpeed"
+	AudioFileSilent                = "AudioFileSilent"
+	AudioFileTooShort              = "AudioFileTooShort"
+	AudioFileLowSampleRate         = "AudioFileLowSampleRate"
+	AudioFileClipped               = "AudioFileClipped"
+	AudioFileNoisy                 = "AudioFileNoisy"
+	AudioFileBufferOverflow        = "AudioFileBufferOverflow"
+	AudioFileInvalidRotation       = "AudioFileInvalidRotation"
+	SpeakerNoSpeech                = "SpeakerNoSpeech"
+	SpeakerInvalidVoiceprint       = "SpeakerInvalidVoiceprint"
+	SpeechUnverifiedSpeaker        = "SpeechUnverifiedSpeaker"
+	APIMalformedResponse           = "APIMalformedResponse"
+	STTInvalidOptions              = "STTInvalidOptions"
+	TTSInvalidOptions              = "TTSInvalidOptions"
+	SSMLInvalid                    = "SSMLInvalid"
+	InterpretMissingEntity         = "InterpretMissingEntity"
+	InterpretInvalidEntity         = "InterpretInvalidEntity"
+	InterpretInvalidTarget         = "InterpretInvalidTarget"
 )
 
 // errorMessages converts an error code to its corresponding message
 var errorMessages = map[ErrorCode]string{
-	SpeechNilAudio: "The audio file was nil. In order to convert a Speech object to Text, it must have a valid audio file. Usually, this means you created a Speech object that wasn't created using one of the Listen methods.",
-	WAVCorruptFile: "The WAV file was corrupted and did not have a correctly formatted RIFF header. Check the file to make sure it was not corrupted or incomplete.",
+	SpeechNilAudio:                 "The audio file was nil. In order to convert a Speech object to Text, it must have a valid audio file. Usually, this means you created a Speech object that wasn't created using one of the Listen methods.",
+	WAVCorruptFile:                 "The WAV file was corrupted and did not have a correctly formatted RIFF header. Check the file to make sure it was not corrupted or incomplete.",
 	AudioFileOutputStreamNotOpened: "PortAudio encountered an error in opening the audio stream, which is usually due to an error in connecting to the input and/or output device.",
-	AudioFileNotWritableStream: "The data could not be written into the stream. You may have attempted to write to a callback stream, tried to write to an input-only stream, created a buffer with incorrect parameters, or did not open the stream at all.",
+	AudioFileNotWritableStream:     "The data could not be written into the stream. You may have attempted to write to a callback stream, tried to write to an input-only stream, created a buffer with incorrect parameters, or did not open the stream at all.",
+	AudioFileInvalidRange:          "The requested range is outside of the audio data. Make sure that the start is not after the end, and that both lie within the duration of the audio.",
+	AudioFileInvalidFormat:         "The requested audio format is not supported. The sample rate and number of channels must be greater than 0, and the bit depth must be 8, 16, 24 or 32 bits.",
+	AudioFileInvalidSpeed:          "The requested playback speed or pitch shift is out of range. The speed must be between 0.25 and 4 times the original, and pitch shifts must be within two octaves (24 semitones) in either direction.",
+	AudioFileSilent:                "The audio is almost entirely silent, so there is no speech to transcribe. Check that the correct input device is selected and that the microphone isn't muted.",
+	AudioFileTooShort:              "The audio is too short to contain any meaningful speech.",
+	AudioFileLowSampleRate:         "The sample rate of the audio is too low for speech to be recognized reliably. Record at 16KHz if possible, and at least 8KHz.",
+	AudioFileClipped:               "Too much of the audio is clipped, which distorts the speech. Lower the recording level or move further from the microphone.",
+	AudioFileNoisy:                 "There is too much background noise compared to the speech. Try recording somewhere quieter, moving closer to the microphone or enabling noise suppression.",
+	AudioFileBufferOverflow:        "Audio was recorded faster than it was consumed, and the recording buffer filled up. Read the recording faster, use a larger buffer, or use a different overflow policy.",
+	AudioFileInvalidRotation:       "The file rotation settings are invalid. The rotation duration must be at least one sample long, and the rotation size must not be negative.",
+	SpeakerNoSpeech:                "There wasn't enough speech in the recordings to enroll the speaker. Provide recordings of the user speaking, ideally totalling 10 seconds or more.",
+	SpeakerInvalidVoiceprint:       "A saved voiceprint is invalid. Every component must have a weight that isn't negative, a mean and variances of the same dimension as the others, and variances greater than 0.",
+	SpeechUnverifiedSpeaker:        "The speech didn't match any of the enrolled speakers, so it wasn't transcribed.",
+	APIMalformedResponse:           "The API returned a response that couldn't be understood. This may be a temporary problem with the service, or the backend may be pointed at the wrong server.",
+	STTInvalidOptions:              "The speech to text options are invalid. The number of alternatives must not be negative, and every hint must have a phrase and a boost that isn't negative.",
+	TTSInvalidOptions:              "The text to speech options are invalid. The rate must be between 0.25 and 4, the pitch shift must be within 24 semitones in either direction, and only WAV and PCM audio (with a sample rate) can be decoded into an audio file.",
+	SSMLInvalid:                    "The SSML document is invalid. It must be well-formed XML with a speak element at its root, and each element must have valid attributes.",
+	InterpretMissingEntity:         "Entities that are required weren't found in the query. Ask the user for them, e.g. with a follow-up question.",
+	InterpretInvalidEntity:         "An entity couldn't be converted to the type of the field it is decoded into, or isn't one of the field's allowed values.",
+	InterpretInvalidTarget:         "Entities can only be decoded into a pointer to a struct, whose tagged fields are strings, numbers, booleans, times, durations, locations or types that implement encoding.TextUnmarshaler.",
 }
-
-
@@ -15,6 +15,15 @@ type Interpret struct {
 	// will be a key-value listing according to the entities described on the
 	// Aurora dashboard.
 	Entities map[string]string
+	// Confidence is how confident the service is in `Intent`, from 0 to 1.
+	Confidence float64
+	// Intents are the candidate intents, ranked from the most to the least
+	// likely, e.g. to ask the user which they meant if the top two are close.
+	Intents []*api.InterpretIntent
+	// EntityDetails are the entities of `Entities`, in the order they appear
+	// in the text, with their types, positions and normalized values (such
+	// as the date that "tomorrow" refers to).
+	EntityDetails []*api.InterpretEntity
 }
 
 // NewInterpret takes a response from the API and creates an Interpet object
@@ -24,5 +33,25 @@ func NewInterpret(res *api.InterpretResponse) *Interpret {
 	if res == nil {
 		return nil
 	}
-	return &Interpret{Intent: res.Intent, Entities: res.Entities}
+	return &Interpret{
+		Intent:        res.Intent,
+		Entities:      res.Entities,
+		Confidence:    res.Confidence,
+		Intents:       res.Intents,
+		EntityDetails: res.EntityDetails,
+	}
+}
+
+// Entity returns the details of the entity with the given name, or `nil` if
+// there isn't one (or `i` is `nil`).
+func (i *Interpret) Entity(name string) *api.InterpretEntity {
+	if i == nil {
+		return nil
+	}
+	for _, e := range i.EntityDetails {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
 }
@@ -1,6 +1,10 @@
 package aurora
 
 import (
+	"fmt"
+	"io"
+	"math"
+
 	"github.com/auroraapi/aurora-go/api"
 	"github.com/auroraapi/aurora-go/audio"
 	"github.com/auroraapi/aurora-go/errors"
@@ -26,13 +30,86 @@ type ListenParams struct {
 	// automatically stopping. This value is only taken into consideration if
 	// `Length` is 0
 	SilenceLen float64
+	// EchoCancellation removes audio played with `audio.File.Play` from the
+	// recording, so that the device doesn't transcribe its own prompts.
+	EchoCancellation bool
+	// Processors are additional processing stages (such as an
+	// `audio.NoiseSuppressor`) that are run, in order, over the audio as it
+	// is recorded, before it is returned or streamed to the API.
+	Processors []audio.Processor
+	// OnEvent, if set, is called while listening with the input level and
+	// whenever speech starts or ends, the recording times out or the device
+	// reports an error (see `audio.EventType`). It can be used to drive a
+	// level meter or "listening" indicator. It is called from the recording
+	// goroutine, so it must return quickly.
+	OnEvent func(audio.Event)
+	// BufferSize and Overflow control how much recorded audio is held while
+	// waiting to be consumed (for example, uploaded to the API) and what
+	// happens if it fills up. See `audio.RecordingParams`.
+	BufferSize int
+	Overflow   audio.OverflowPolicy
+	// WakeWord, if set, gates `ContinuouslyListen` and
+	// `ContinuouslyListenAndTranscribe` so that utterances are ignored until
+	// one contains the wake phrase (see `audio.TemplateSpotter`). Only the
+	// speech following the phrase is passed on: the rest of the same
+	// utterance if there is any, otherwise the next utterance.
+	WakeWord audio.KeywordSpotter
+	// STTOptions configure how the speech is transcribed, both by the
+	// functions that transcribe as they listen and by `Speech.Text` on the
+	// speech that is returned. `nil` means the defaults.
+	STTOptions *api.STTOptions
+
+	// echoCanceller, if set, is the echo canceller used for every utterance
+	// of a listening session, so that the echo path it has learned is kept
+	// between utterances.
+	echoCanceller *audio.EchoCanceller
 }
 
 // NewListenParams creates the default set of ListenParams. You should
 // call this function to get the default and then replace the ones you
 // want to customize.
 func NewListenParams() *ListenParams {
-	return &ListenParams{ListenDefaultLength, ListenDefaultSilenceLen}
+	return &ListenParams{
+		Length:     ListenDefaultLength,
+		SilenceLen: ListenDefaultSilenceLen,
+	}
+}
+
+// session returns a copy of the listen parameters for a session that listens
+// for several utterances, which shares state (such as the echo canceller)
+// between the utterances.
+func (p *ListenParams) session() *ListenParams {
+	session := *p
+	if session.EchoCancellation && session.echoCanceller == nil {
+		session.echoCanceller = audio.NewEchoCanceller(audio.SampleRate, audio.DefaultEchoTailLen)
+	}
+	return &session
+}
+
+// recordingParams converts the listen parameters into parameters for the
+// recording framework. The returned function must be called once recording
+// has finished to release any resources held by the processing stages.
+func (p *ListenParams) recordingParams() (*audio.RecordingParams, func()) {
+	params := &audio.RecordingParams{
+		Length:     p.Length,
+		SilenceLen: p.SilenceLen,
+		OnEvent:    p.OnEvent,
+		BufferSize: p.BufferSize,
+		Overflow:   p.Overflow,
+	}
+
+	done := func() {}
+	if p.EchoCancellation {
+		ec := p.echoCanceller
+		if ec == nil {
+			ec = audio.NewEchoCanceller(audio.SampleRate, audio.DefaultEchoTailLen)
+		}
+		ec.AttachPlayback()
+		params.Processors = append(params.Processors, ec)
+		done = ec.DetachPlayback
+	}
+	params.Processors = append(params.Processors, p.Processors...)
+	return params, done
 }
 
 // SpeechHandleFunc is the type of function that is passed to `ContinuouslyListen`.
@@ -58,6 +135,16 @@ type Speech struct {
 	// a speech object and set this directly if you want to operate on some
 	// pre-recorded audio
 	Audio *audio.File
+	// Speaker, if set, is used to check that the speech was spoken by an
+	// enrolled user before it is transcribed by `Text` (see
+	// `audio.GMMVerifier`).
+	Speaker audio.SpeakerVerifier
+	// STTOptions configure how the speech is transcribed by `Text` and
+	// `Transcript` (`nil` means the defaults).
+	STTOptions *api.STTOptions
+	// Timepoints are when each word (and each mark, for SSML) is spoken, in
+	// order. They are only set for speech from `Text.TimedSpeech`.
+	Timepoints []*api.TTSTimepoint
 }
 
 // NewSpeech creates a Speech object from the given audio file.
@@ -65,19 +152,116 @@ func NewSpeech(newAudio *audio.File) *Speech {
 	return &Speech{Audio: newAudio}
 }
 
+// TimepointsBetween returns the timepoints that start at or after `from`
+// seconds and before `to` seconds.
+func (t *Speech) TimepointsBetween(from float64, to float64) []*api.TTSTimepoint {
+	timepoints := make([]*api.TTSTimepoint, 0)
+	for _, tp := range t.Timepoints {
+		if tp.Start >= from && tp.Start < to {
+			timepoints = append(timepoints, tp)
+		}
+	}
+	return timepoints
+}
+
+// PlayWithTimepoints plays the audio, calling `onTimepoint` with each
+// timepoint as playback reaches it, e.g. to highlight words as they are
+// spoken or move an avatar's lips. It is called from the playback loop, so it
+// must return quickly.
+func (t *Speech) PlayWithTimepoints(onTimepoint func(*api.TTSTimepoint)) error {
+	played := 0.0
+	err := t.Audio.PlayWithProgress(func(seconds float64) {
+		for _, tp := range t.TimepointsBetween(played, seconds) {
+			onTimepoint(tp)
+		}
+		played = seconds
+	})
+	if err != nil {
+		return err
+	}
+	// anything at the very end
+	for _, tp := range t.TimepointsBetween(played, math.Inf(1)) {
+		onTimepoint(tp)
+	}
+	return nil
+}
+
 // Text calls the Aurora STT API and converts a user's utterance into
 // a text transcription. This is populated into a `Text` object, allowing you
-// to chain and combine high-level abstractions.
+// to chain and combine high-level abstractions. If `Speaker` is set, speech
+// that doesn't come from an enrolled user is rejected without calling the API
+// (see `VerifySpeaker`).
 func (t *Speech) Text() (*Text, error) {
 	if t.Audio == nil {
 		return nil, errors.NewFromErrorCode(errors.SpeechNilAudio)
 	}
+	if _, err := t.VerifySpeaker(); err != nil {
+		return nil, err
+	}
 
-	response, err := api.GetSTT(Config, t.Audio)
+	response, err := api.GetSTTWithOptions(Config, t.Audio, t.STTOptions)
 	if err != nil {
 		return nil, err
 	}
-	return NewText(response.Transcript), nil
+	return newTextFromSTT(response), nil
+}
+
+// VerifySpeaker checks who spoke the speech using `Speaker`. It returns the
+// closest enrolled user, along with a `SpeechUnverifiedSpeaker` error if the
+// match isn't good enough. If `Speaker` isn't set, it returns nil without an
+// error.
+func (t *Speech) VerifySpeaker() (*audio.SpeakerMatch, error) {
+	if t.Speaker == nil {
+		return nil, nil
+	}
+	if t.Audio == nil {
+		return nil, errors.NewFromErrorCode(errors.SpeechNilAudio)
+	}
+
+	match := t.Speaker.Verify(t.Audio)
+	if !match.Accepted {
+		info := fmt.Sprintf("Closest speaker %q scored %.2f.", match.Name, match.Score)
+		return match, errors.NewFromErrorCodeInfo(errors.SpeechUnverifiedSpeaker, info)
+	}
+	return match, nil
+}
+
+// TranscriptSegment is the transcription of a single utterance within a
+// longer piece of speech.
+type TranscriptSegment struct {
+	// Start and End are the position (in seconds) of the utterance within
+	// the speech.
+	Start float64
+	End   float64
+	// Text is the transcription of the utterance.
+	Text *Text
+}
+
+// Transcript splits the speech into utterances separated by silence (see
+// `audio.File.Segment`) and transcribes each of them with the Aurora STT API,
+// returning a timestamped transcript. This is useful for long recordings that
+// contain many utterances. Pass `nil` to use the default segmentation
+// parameters. If a transcription fails, the segments transcribed so far are
+// returned along with the error.
+func (t *Speech) Transcript(params *audio.SegmentParams) ([]*TranscriptSegment, error) {
+	if t.Audio == nil {
+		return nil, errors.NewFromErrorCode(errors.SpeechNilAudio)
+	}
+
+	segments := t.Audio.Segment(params)
+	transcript := make([]*TranscriptSegment, 0, len(segments))
+	for _, s := range segments {
+		response, err := api.GetSTTWithOptions(Config, s.Audio, t.STTOptions)
+		if err != nil {
+			return transcript, err
+		}
+		transcript = append(transcript, &TranscriptSegment{
+			Start: s.Start,
+			End:   s.End,
+			Text:  newTextFromSTT(response),
+		})
+	}
+	return transcript, nil
 }
 
 // `Listen` takes in `ListenParams` and generates a speech object based on those
@@ -97,11 +281,14 @@ func Listen(params *ListenParams) (*Speech, error) {
 		params = NewListenParams()
 	}
 
-	audio, err := audio.NewFileFromRecording(params.Length, params.SilenceLen)
+	recParams, done := params.recordingParams()
+	defer done()
+
+	audio, err := audio.NewFileFromRecordingParams(recParams)
 	if err != nil {
 		return nil, err
 	}
-	return &Speech{Audio: audio}, nil
+	return &Speech{Audio: audio, STTOptions: params.STTOptions}, nil
 }
 
 // ContinuouslyListen calls `Listen` continuously.
@@ -116,19 +303,51 @@ func Listen(params *ListenParams) (*Speech, error) {
 // This function accepts another function as an argument that is called each time
 // a speech utterance is decoded. See the documentation for `SpeechHandleFunc`
 // for more information.
+//
+// If `params.WakeWord` is set, only speech following the wake phrase is passed
+// to the handler.
 func ContinuouslyListen(params *ListenParams, handleFunc SpeechHandleFunc) {
 	if params == nil {
 		params = NewListenParams()
 	}
+	params = params.session()
 
 	for {
-		s, err := Listen(params)
+		s, err := listenGated(params)
 		if !handleFunc(s, err) {
 			break
 		}
 	}
 }
 
+// listenGated listens for an utterance. If there is a wake word, it waits for
+// an utterance containing the wake phrase and returns the speech following
+// it instead.
+func listenGated(params *ListenParams) (*Speech, error) {
+	if params.WakeWord == nil {
+		return Listen(params)
+	}
+
+	for {
+		s, err := Listen(params)
+		if err != nil {
+			return nil, err
+		}
+		found, end := params.WakeWord.Spot(s.Audio)
+		if !found {
+			continue
+		}
+
+		// use the rest of the utterance if the command followed the phrase
+		// without a pause
+		rest, err := s.Audio.Slice(end, s.Audio.Duration())
+		if err == nil && len(rest.Segment(nil)) > 0 {
+			return &Speech{Audio: rest, STTOptions: params.STTOptions}, nil
+		}
+		return Listen(params)
+	}
+}
+
 // ListenAndTranscribe starts listening with the given parameters, except instead
 // of waiting for the audio to finish capturing and returning a Speech object,
 // it directly streams it to the API, transcribing it in real-time. When the
@@ -150,12 +369,43 @@ func ListenAndTranscribe(params *ListenParams) (*Text, error) {
 	// create a new recording stream and begin recording. Data will automatically
 	// be written to the stream as it becomes available, so we can directly call
 	// the API with this stream while audio is recording
-	stream := audio.NewRecordingStream(params.Length, params.SilenceLen)
-	response, err := api.GetSTTFromStream(Config, stream)
+	recParams, done := params.recordingParams()
+	defer done()
+
+	stream := audio.NewRecordingStreamFromParams(recParams)
+	defer stream.Close()
+	response, err := api.GetSTTFromStreamWithOptions(Config, stream, params.STTOptions)
 	if err != nil {
 		return nil, err
 	}
-	return NewText(response.Transcript), nil
+	return newTextFromSTT(response), nil
+}
+
+// ListenAndStream is like `ListenAndTranscribe`, but uses the streaming speech
+// to text API, so that interim transcripts can be shown while the user is
+// still speaking. The hypotheses are received from the `Results` channel of the
+// returned stream (see `api.STTStream`), which is closed once the recording
+// has finished and the final transcript has been received. Pass `nil` to use
+// the default parameters.
+func ListenAndStream(params *ListenParams) *api.STTStream {
+	if params == nil {
+		params = NewListenParams()
+	}
+
+	recParams, done := params.recordingParams()
+	recording := audio.NewRecordingStreamFromParams(recParams)
+	stream := api.NewSTTStreamWithOptions(Config, params.STTOptions)
+	go func() {
+		defer done()
+		// stops recording if the session ended early
+		defer recording.Close()
+		if _, err := io.Copy(stream, recording); err != nil {
+			stream.CloseWithError(err)
+			return
+		}
+		stream.Close()
+	}()
+	return stream
 }
 
 // ContinuouslyListenAndTranscribe is a combination of `ContinuouslyListen`
@@ -163,13 +413,29 @@ func ListenAndTranscribe(params *ListenParams) (*Text, error) {
 // understand how it works. The difference is that this handler function receives
 // objects of type *Text instead of *Speech. See the documentation for `TextHandleFunc`
 // for more information on that.
+//
+// If `params.WakeWord` is set, only speech following the wake phrase is
+// transcribed. Since the phrase has to be found first, each utterance is
+// recorded in full before it is sent to the API rather than streamed.
 func ContinuouslyListenAndTranscribe(params *ListenParams, handleFunc TextHandleFunc) {
 	if params == nil {
 		params = NewListenParams()
 	}
+	params = params.session()
 
 	for {
-		t, err := ListenAndTranscribe(params)
+		var t *Text
+		var err error
+		if params.WakeWord == nil {
+			t, err = ListenAndTranscribe(params)
+		} else {
+			// the audio has to be checked for the wake phrase before it can
+			// be sent to the API, so it can't be streamed
+			var s *Speech
+			if s, err = listenGated(params); err == nil {
+				t, err = s.Text()
+			}
+		}
 		if !handleFunc(t, err) {
 			break
 		}
@@ -0,0 +1,216 @@
+package aurora_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	aurora "github.com/auroraapi/aurora-go"
+	"github.com/auroraapi/aurora-go/api"
+	"github.com/auroraapi/aurora-go/api/backend"
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+// withBackend points the SDK at the given handler for the duration of a
+// test.
+func withBackend(t *testing.T, handler http.HandlerFunc) {
+	s := httptest.NewServer(handler)
+	original := aurora.Config.Backend
+	aurora.Config.Backend = backend.NewAuroraBackendWithClient(s.URL, s.Client())
+	t.Cleanup(func() {
+		aurora.Config.Backend = original
+		s.Close()
+	})
+}
+
+// received returns the next request that a stub handler passed to
+// `requests`. Handlers run on the server's goroutines, where failing the test
+// doesn't stop it, so they hand requests over to be checked by the test.
+func received(t *testing.T, requests <-chan *http.Request) *http.Request {
+	select {
+	case r := <-requests:
+		return r
+	default:
+		t.Fatal("no request was received")
+		return nil
+	}
+}
+
+// Each utterance is transcribed separately and returned with its position.
+func TestSpeechTranscript(t *testing.T) {
+	var calls int32
+	paths, uploads := make(chan string, 2), make(chan error, 2)
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		_, err := audio.NewFileFromReader(r.Body)
+		uploads <- err
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"transcript": fmt.Sprintf("utterance %d", atomic.AddInt32(&calls, 1)),
+		})
+	})
+
+	samples := make([]float64, 5*16000)
+	copy(samples[16000:], testutils.Sine(300, 0.5, 16000, 16000))
+	copy(samples[3*16000:], testutils.Sine(300, 0.5, 16000, 16000/2))
+	speech := aurora.NewSpeech(testutils.NewFile(16000, samples))
+
+	transcript, err := speech.Transcript(nil)
+	require.Nil(t, err)
+	require.Len(t, transcript, 2)
+	require.Len(t, paths, 2)
+	for i := 0; i < 2; i++ {
+		require.Equal(t, "/v1/stt/", <-paths)
+		require.Nil(t, <-uploads)
+	}
+	require.Equal(t, "utterance 1", transcript[0].Text.Text)
+	require.Equal(t, "utterance 2", transcript[1].Text.Text)
+	require.InDelta(t, 0.9, transcript[0].Start, 0.07)
+	require.InDelta(t, 3.6, transcript[1].End, 0.07)
+}
+
+// Errors from the API are returned along with whatever was transcribed.
+func TestSpeechTranscriptError(t *testing.T) {
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"code": "APIInvalidInput"})
+	})
+
+	samples := make([]float64, 3*16000)
+	copy(samples[16000:], testutils.Sine(300, 0.5, 16000, 16000))
+	transcript, err := aurora.NewSpeech(testutils.NewFile(16000, samples)).Transcript(nil)
+	require.NotNil(t, err)
+	require.IsType(t, apiErrorType, err)
+	require.Len(t, transcript, 0)
+
+	_, err = aurora.NewSpeech(nil).Transcript(nil)
+	require.NotNil(t, err)
+}
+
+// With a quality check configured, unusable audio is rejected before it is
+// uploaded, or passed to the warning handler if there is one.
+func TestSpeechTextQualityCheck(t *testing.T) {
+	requests := make(chan *http.Request, 2)
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"transcript": "hello"})
+	})
+	aurora.Config.QualityCheck = audio.NewQualityParams()
+	defer func() {
+		aurora.Config.QualityCheck = nil
+		aurora.Config.QualityWarning = nil
+	}()
+
+	silent := aurora.NewSpeech(testutils.NewFile(16000, make([]float64, 16000)))
+	_, err := silent.Text()
+	require.NotNil(t, err)
+	require.Equal(t, errors.AudioFileSilent, err.(*errors.Error).Code)
+	require.Len(t, requests, 0)
+
+	var warnings []*errors.Error
+	aurora.Config.QualityWarning = func(problems []*errors.Error) {
+		warnings = problems
+	}
+	text, err := silent.Text()
+	require.Nil(t, err)
+	require.Equal(t, "hello", text.Text)
+	require.Len(t, warnings, 1)
+	require.Len(t, requests, 1)
+}
+
+// verifierFunc adapts a function to the audio.SpeakerVerifier interface.
+type verifierFunc func(f *audio.File) *audio.SpeakerMatch
+
+func (v verifierFunc) Verify(f *audio.File) *audio.SpeakerMatch {
+	return v(f)
+}
+
+// Speech from an unverified speaker isn't transcribed.
+func TestSpeechTextSpeaker(t *testing.T) {
+	requests := make(chan *http.Request, 2)
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"transcript": "hello"})
+	})
+
+	accepted := false
+	speech := aurora.NewSpeech(testutils.NewFile(16000, testutils.Sine(300, 0.5, 16000, 16000)))
+	speech.Speaker = verifierFunc(func(f *audio.File) *audio.SpeakerMatch {
+		return &audio.SpeakerMatch{Name: "alice", Score: -2, Accepted: accepted}
+	})
+
+	match, err := speech.VerifySpeaker()
+	require.NotNil(t, err)
+	require.Equal(t, errors.SpeechUnverifiedSpeaker, err.(*errors.Error).Code)
+	require.Equal(t, "alice", match.Name)
+	_, err = speech.Text()
+	require.NotNil(t, err)
+	require.Len(t, requests, 0)
+
+	accepted = true
+	text, err := speech.Text()
+	require.Nil(t, err)
+	require.Equal(t, "hello", text.Text)
+	require.Len(t, requests, 1)
+}
+
+// Details of the transcript, including alternatives, are available on the
+// text.
+func TestSpeechTextAlternatives(t *testing.T) {
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"transcript": "recognize speech",
+			"confidence": 0.9,
+			"language": "en-US",
+			"words": [{"word": "recognize", "start": 0.1, "end": 0.6}, {"word": "speech", "start": 0.6, "end": 1.0}],
+			"alternatives": [
+				{"transcript": "recognize speech", "confidence": 0.9},
+				{"transcript": "wreck a nice beach", "confidence": 0.4, "words": [{"word": "wreck"}]}
+			]
+		}`))
+	})
+
+	text, err := aurora.NewSpeech(testutils.NewFile(16000, testutils.Sine(300, 0.5, 16000, 16000))).Text()
+	require.Nil(t, err)
+	require.Equal(t, "recognize speech", text.Text)
+	require.Equal(t, 0.9, text.Confidence)
+	require.Equal(t, "en-US", text.Language)
+	require.Len(t, text.Words, 2)
+	require.Equal(t, 1.0, text.Words[1].End)
+
+	require.Len(t, text.Alternatives, 1)
+	alt := text.Alternatives[0]
+	require.Equal(t, "wreck a nice beach", alt.Text)
+	require.Equal(t, 0.4, alt.Confidence)
+	require.Equal(t, "wreck", alt.Words[0].Word)
+	require.Equal(t, "en-US", alt.Language)
+}
+
+// The speech's options are sent when it is transcribed.
+func TestSpeechTextOptions(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write([]byte(`{"transcript": "¡Hola!"}`))
+	})
+
+	speech := aurora.NewSpeech(testutils.NewFile(16000, testutils.Sine(300, 0.5, 16000, 16000)))
+	speech.STTOptions = &api.STTOptions{Language: "es-MX", Punctuation: true}
+	text, err := speech.Text()
+	require.Nil(t, err)
+	require.Equal(t, "¡Hola!", text.Text)
+	q := received(t, requests).URL.Query()
+	require.Equal(t, "es-MX", q.Get("language"))
+	require.Equal(t, "true", q.Get("punctuation"))
+}
@@ -0,0 +1,463 @@
+// Package ssml builds Speech Synthesis Markup Language (SSML) documents, which
+// control how text is spoken by TTS: pauses, emphasis, speaking rate and
+// pitch, how numbers and dates are read, pronunciations, etc. Documents are
+// validated and escaped as they are built, so a mistake is reported before
+// the text is sent to the API.
+//
+//	doc, err := ssml.New("en-US").
+//		Text("Your code is").
+//		SayAs(ssml.InterpretCharacters, "", "A7X").
+//		Break(500 * time.Millisecond).
+//		Prosody(ssml.Prosody{Rate: "slow"}, ssml.Text("Goodbye!")).
+//		Build()
+package ssml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/auroraapi/aurora-go/errors"
+)
+
+// MaxBreak is the longest pause that can be inserted with a break.
+const MaxBreak = 10 * time.Second
+
+// InterpretAs says how the text of a say-as element is read.
+type InterpretAs string
+
+// Common say-as interpretations.
+const (
+	// InterpretCharacters spells the text out letter by letter.
+	InterpretCharacters InterpretAs = "characters"
+	// InterpretCardinal reads a number as a cardinal number ("twelve").
+	InterpretCardinal InterpretAs = "cardinal"
+	// InterpretOrdinal reads a number as an ordinal number ("twelfth").
+	InterpretOrdinal InterpretAs = "ordinal"
+	// InterpretDigits reads a number digit by digit.
+	InterpretDigits InterpretAs = "digits"
+	// InterpretFraction reads a fraction such as "3/4".
+	InterpretFraction InterpretAs = "fraction"
+	// InterpretUnit reads a measurement such as "5kg".
+	InterpretUnit InterpretAs = "unit"
+	// InterpretDate reads a date, in the order given by the format (such as
+	// "mdy" or "ymd").
+	InterpretDate InterpretAs = "date"
+	// InterpretTime reads a time of day or a duration.
+	InterpretTime InterpretAs = "time"
+	// InterpretTelephone reads a phone number.
+	InterpretTelephone InterpretAs = "telephone"
+	// InterpretAddress reads a postal address.
+	InterpretAddress InterpretAs = "address"
+	// InterpretExpletive bleeps the text out.
+	InterpretExpletive InterpretAs = "expletive"
+)
+
+// BreakStrength is the strength of a pause, relative to the pauses between
+// words and sentences.
+type BreakStrength string
+
+// Break strengths, from no pause to the longest.
+const (
+	BreakNone    BreakStrength = "none"
+	BreakXWeak   BreakStrength = "x-weak"
+	BreakWeak    BreakStrength = "weak"
+	BreakMedium  BreakStrength = "medium"
+	BreakStrong  BreakStrength = "strong"
+	BreakXStrong BreakStrength = "x-strong"
+)
+
+// EmphasisLevel is how strongly text is emphasised.
+type EmphasisLevel string
+
+// Emphasis levels, from de-emphasised to the strongest.
+const (
+	EmphasisReduced  EmphasisLevel = "reduced"
+	EmphasisNone     EmphasisLevel = "none"
+	EmphasisModerate EmphasisLevel = "moderate"
+	EmphasisStrong   EmphasisLevel = "strong"
+)
+
+// Phonetic alphabets for phoneme elements.
+const (
+	AlphabetIPA    = "ipa"
+	AlphabetXSAMPA = "x-sampa"
+)
+
+// Node is an element (or text) in an SSML document.
+type Node interface {
+	validate() error
+	write(w *bytes.Buffer)
+}
+
+// Text is plain text. Characters with a special meaning in XML are escaped.
+type Text string
+
+// Break is a pause, given either as a time or a strength.
+type Break struct {
+	Time     time.Duration
+	Strength BreakStrength
+}
+
+// Emphasis speaks the text it contains with more or less emphasis.
+type Emphasis struct {
+	Level    EmphasisLevel
+	Children []Node
+}
+
+// Prosody changes the rate, pitch or volume of the text it contains. Each
+// attribute can be a keyword or a relative change, and at least one must be
+// set:
+//   - Rate: "x-slow" to "x-fast", or a percentage of normal such as "80%".
+//   - Pitch: "x-low" to "x-high", or a change such as "+10%", "-2st" or
+//     "+50Hz".
+//   - Volume: "silent", "x-soft" to "x-loud", or a change such as "+6dB".
+type Prosody struct {
+	Rate     string
+	Pitch    string
+	Volume   string
+	Children []Node
+}
+
+// SayAs says how text such as a number, date or phone number is read. Format
+// further describes the text for some interpretations (such as the order of
+// a date).
+type SayAs struct {
+	InterpretAs InterpretAs
+	Format      string
+	Text        string
+}
+
+// Sub speaks `Alias` in place of `Text`, e.g. "World Wide Web Consortium"
+// for "W3C".
+type Sub struct {
+	Alias string
+	Text  string
+}
+
+// Phoneme gives the pronunciation of `Text` in a phonetic alphabet.
+type Phoneme struct {
+	Alphabet string
+	PH       string
+	Text     string
+}
+
+// Mark is a named position in the document, which is reported when it is
+// reached while the speech is played.
+type Mark struct {
+	Name string
+}
+
+// Document is a complete SSML document (a speak element).
+type Document struct {
+	// Lang is the language of the document, as a BCP-47 tag such as
+	// "en-US" (optional).
+	Lang     string
+	Children []Node
+}
+
+var (
+	rateKeywords   = keywords("x-slow", "slow", "medium", "fast", "x-fast", "default")
+	pitchKeywords  = keywords("x-low", "low", "medium", "high", "x-high", "default")
+	volumeKeywords = keywords("silent", "x-soft", "soft", "medium", "loud", "x-loud", "default")
+	breakStrengths = keywords(string(BreakNone), string(BreakXWeak), string(BreakWeak), string(BreakMedium), string(BreakStrong), string(BreakXStrong))
+	emphasisLevels = keywords(string(EmphasisReduced), string(EmphasisNone), string(EmphasisModerate), string(EmphasisStrong))
+
+	ratePattern   = regexp.MustCompile(`^\d+(\.\d+)?%$`)
+	pitchPattern  = regexp.MustCompile(`^[+-]\d+(\.\d+)?(%|st|Hz)$`)
+	volumePattern = regexp.MustCompile(`^[+-]\d+(\.\d+)?dB$`)
+	namePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+// Builder builds an SSML document one node at a time.
+type Builder struct {
+	doc *Document
+}
+
+// New starts building a document in the given language (which may be empty).
+func New(lang string) *Builder {
+	return &Builder{doc: &Document{Lang: lang}}
+}
+
+// Add appends nodes to the document.
+func (b *Builder) Add(nodes ...Node) *Builder {
+	b.doc.Children = append(b.doc.Children, nodes...)
+	return b
+}
+
+// Text appends plain text.
+func (b *Builder) Text(text string) *Builder {
+	return b.Add(Text(text))
+}
+
+// Break appends a pause of the given length.
+func (b *Builder) Break(d time.Duration) *Builder {
+	return b.Add(&Break{Time: d})
+}
+
+// Pause appends a pause of the given strength.
+func (b *Builder) Pause(strength BreakStrength) *Builder {
+	return b.Add(&Break{Strength: strength})
+}
+
+// Emphasis appends nodes spoken with the given emphasis.
+func (b *Builder) Emphasis(level EmphasisLevel, children ...Node) *Builder {
+	return b.Add(&Emphasis{Level: level, Children: children})
+}
+
+// Prosody appends nodes spoken with the rate, pitch and volume of `p` (its
+// children are added to those in `p`).
+func (b *Builder) Prosody(p Prosody, children ...Node) *Builder {
+	p.Children = append(p.Children, children...)
+	return b.Add(&p)
+}
+
+// SayAs appends text read according to `interpretAs` and `format`.
+func (b *Builder) SayAs(interpretAs InterpretAs, format string, text string) *Builder {
+	return b.Add(&SayAs{InterpretAs: interpretAs, Format: format, Text: text})
+}
+
+// Sub appends text that is spoken as `alias`.
+func (b *Builder) Sub(text string, alias string) *Builder {
+	return b.Add(&Sub{Alias: alias, Text: text})
+}
+
+// Phoneme appends text with the given pronunciation.
+func (b *Builder) Phoneme(alphabet string, ph string, text string) *Builder {
+	return b.Add(&Phoneme{Alphabet: alphabet, PH: ph, Text: text})
+}
+
+// Mark appends a named mark.
+func (b *Builder) Mark(name string) *Builder {
+	return b.Add(&Mark{Name: name})
+}
+
+// Build validates the document and returns it. It returns an `SSMLInvalid`
+// error describing the first problem found.
+func (b *Builder) Build() (*Document, error) {
+	if err := b.doc.Validate(); err != nil {
+		return nil, err
+	}
+	return b.doc, nil
+}
+
+// Validate checks every element of the document.
+func (d *Document) Validate() error {
+	return validateAll(d.Children)
+}
+
+// String returns the document as SSML markup.
+func (d *Document) String() string {
+	w := &bytes.Buffer{}
+	w.WriteString("<speak")
+	attr(w, "xml:lang", d.Lang)
+	w.WriteString(">")
+	writeAll(w, d.Children)
+	w.WriteString("</speak>")
+	return w.String()
+}
+
+// Check checks that hand-written SSML is well-formed XML with a speak
+// element at its root. It doesn't check the elements themselves.
+func Check(markup string) error {
+	decoder := xml.NewDecoder(strings.NewReader(markup))
+	root := ""
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return invalid(err.Error())
+		}
+		if start, ok := token.(xml.StartElement); ok && root == "" {
+			root = start.Name.Local
+		}
+	}
+	if root != "speak" {
+		return invalid("The document must be a speak element.")
+	}
+	return nil
+}
+
+func (t Text) validate() error {
+	return nil
+}
+
+func (t Text) write(w *bytes.Buffer) {
+	xml.EscapeText(w, []byte(t))
+}
+
+func (b *Break) validate() error {
+	if b.Time < 0 || b.Time > MaxBreak {
+		return invalid(fmt.Sprintf("A break of %s is out of range (0 to %s).", b.Time, MaxBreak))
+	}
+	if b.Strength != "" && !breakStrengths[string(b.Strength)] {
+		return invalid(fmt.Sprintf("Unknown break strength %q.", b.Strength))
+	}
+	if b.Time == 0 && b.Strength == "" {
+		return invalid("A break needs a time or a strength.")
+	}
+	return nil
+}
+
+func (b *Break) write(w *bytes.Buffer) {
+	w.WriteString("<break")
+	if b.Time > 0 {
+		attr(w, "time", fmt.Sprintf("%dms", b.Time/time.Millisecond))
+	}
+	attr(w, "strength", string(b.Strength))
+	w.WriteString("/>")
+}
+
+func (e *Emphasis) validate() error {
+	if e.Level != "" && !emphasisLevels[string(e.Level)] {
+		return invalid(fmt.Sprintf("Unknown emphasis level %q.", e.Level))
+	}
+	return validateAll(e.Children)
+}
+
+func (e *Emphasis) write(w *bytes.Buffer) {
+	w.WriteString("<emphasis")
+	attr(w, "level", string(e.Level))
+	w.WriteString(">")
+	writeAll(w, e.Children)
+	w.WriteString("</emphasis>")
+}
+
+func (p *Prosody) validate() error {
+	if p.Rate == "" && p.Pitch == "" && p.Volume == "" {
+		return invalid("Prosody needs a rate, pitch or volume.")
+	}
+	if p.Rate != "" && !rateKeywords[p.Rate] && !ratePattern.MatchString(p.Rate) {
+		return invalid(fmt.Sprintf("Invalid prosody rate %q.", p.Rate))
+	}
+	if p.Pitch != "" && !pitchKeywords[p.Pitch] && !pitchPattern.MatchString(p.Pitch) {
+		return invalid(fmt.Sprintf("Invalid prosody pitch %q.", p.Pitch))
+	}
+	if p.Volume != "" && !volumeKeywords[p.Volume] && !volumePattern.MatchString(p.Volume) {
+		return invalid(fmt.Sprintf("Invalid prosody volume %q.", p.Volume))
+	}
+	return validateAll(p.Children)
+}
+
+func (p *Prosody) write(w *bytes.Buffer) {
+	w.WriteString("<prosody")
+	attr(w, "rate", p.Rate)
+	attr(w, "pitch", p.Pitch)
+	attr(w, "volume", p.Volume)
+	w.WriteString(">")
+	writeAll(w, p.Children)
+	w.WriteString("</prosody>")
+}
+
+func (s *SayAs) validate() error {
+	if s.InterpretAs == "" || s.Text == "" {
+		return invalid("Say-as needs an interpretation and some text.")
+	}
+	return nil
+}
+
+func (s *SayAs) write(w *bytes.Buffer) {
+	w.WriteString("<say-as")
+	attr(w, "interpret-as", string(s.InterpretAs))
+	attr(w, "format", s.Format)
+	w.WriteString(">")
+	xml.EscapeText(w, []byte(s.Text))
+	w.WriteString("</say-as>")
+}
+
+func (s *Sub) validate() error {
+	if s.Alias == "" || s.Text == "" {
+		return invalid("Sub needs an alias and some text.")
+	}
+	return nil
+}
+
+func (s *Sub) write(w *bytes.Buffer) {
+	w.WriteString("<sub")
+	attr(w, "alias", s.Alias)
+	w.WriteString(">")
+	xml.EscapeText(w, []byte(s.Text))
+	w.WriteString("</sub>")
+}
+
+func (p *Phoneme) validate() error {
+	if p.Alphabet != AlphabetIPA && p.Alphabet != AlphabetXSAMPA {
+		return invalid(fmt.Sprintf("Unknown phonetic alphabet %q.", p.Alphabet))
+	}
+	if p.PH == "" || p.Text == "" {
+		return invalid("Phoneme needs a pronunciation and some text.")
+	}
+	return nil
+}
+
+func (p *Phoneme) write(w *bytes.Buffer) {
+	w.WriteString("<phoneme")
+	attr(w, "alphabet", p.Alphabet)
+	attr(w, "ph", p.PH)
+	w.WriteString(">")
+	xml.EscapeText(w, []byte(p.Text))
+	w.WriteString("</phoneme>")
+}
+
+func (m *Mark) validate() error {
+	if !namePattern.MatchString(m.Name) {
+		return invalid(fmt.Sprintf("Invalid mark name %q.", m.Name))
+	}
+	return nil
+}
+
+func (m *Mark) write(w *bytes.Buffer) {
+	w.WriteString("<mark")
+	attr(w, "name", m.Name)
+	w.WriteString("/>")
+}
+
+// validateAll validates each of the nodes.
+func validateAll(nodes []Node) error {
+	for _, n := range nodes {
+		if n == nil {
+			return invalid("A node is nil.")
+		}
+		if err := n.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAll writes each of the nodes.
+func writeAll(w *bytes.Buffer, nodes []Node) {
+	for _, n := range nodes {
+		n.write(w)
+	}
+}
+
+// attr writes an attribute, if it has a value.
+func attr(w *bytes.Buffer, name string, value string) {
+	if value == "" {
+		return
+	}
+	w.WriteString(" " + name + `="`)
+	xml.EscapeText(w, []byte(value))
+	w.WriteString(`"`)
+}
+
+// keywords returns a set of the given words.
+func keywords(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// invalid creates an `SSMLInvalid` error.
+func invalid(info string) error {
+	return errors.NewFromErrorCodeInfo(errors.SSMLInvalid, info)
+}
@@ -0,0 +1,84 @@
+package ssml_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/ssml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	doc, err := ssml.New("en-US").
+		Text("Your code is").
+		SayAs(ssml.InterpretCharacters, "", "A7X").
+		Break(500*time.Millisecond).
+		Emphasis(ssml.EmphasisStrong, ssml.Text("now")).
+		Prosody(ssml.Prosody{Rate: "80%", Pitch: "+2st"}, ssml.Text("slowly")).
+		Sub("W3C", "World Wide Web Consortium").
+		Phoneme(ssml.AlphabetIPA, "təˈmɑːtəʊ", "tomato").
+		Mark("end").
+		Build()
+	require.Nil(t, err)
+	require.Equal(t, `<speak xml:lang="en-US">Your code is`+
+		`<say-as interpret-as="characters">A7X</say-as>`+
+		`<break time="500ms"/>`+
+		`<emphasis level="strong">now</emphasis>`+
+		`<prosody rate="80%" pitch="+2st">slowly</prosody>`+
+		`<sub alias="World Wide Web Consortium">W3C</sub>`+
+		`<phoneme alphabet="ipa" ph="təˈmɑːtəʊ">tomato</phoneme>`+
+		`<mark name="end"/></speak>`, doc.String())
+	require.Nil(t, ssml.Check(doc.String()))
+}
+
+func TestBuildEscapes(t *testing.T) {
+	doc, err := ssml.New("").
+		Text(`Tom & Jerry <3`).
+		Sub("R&D", `"research" & development`).
+		Build()
+	require.Nil(t, err)
+	require.Equal(t, `<speak>Tom &amp; Jerry &lt;3`+
+		`<sub alias="&#34;research&#34; &amp; development">R&amp;D</sub></speak>`, doc.String())
+	require.Nil(t, ssml.Check(doc.String()))
+}
+
+func TestBuildInvalid(t *testing.T) {
+	invalid := []ssml.Node{
+		&ssml.Break{},
+		&ssml.Break{Time: 11 * time.Second},
+		&ssml.Break{Strength: "loud"},
+		&ssml.Emphasis{Level: "extreme"},
+		&ssml.Prosody{},
+		&ssml.Prosody{Rate: "-10%"},
+		&ssml.Prosody{Pitch: "2st"},
+		&ssml.Prosody{Volume: "+6"},
+		&ssml.Prosody{Rate: "slow", Children: []ssml.Node{&ssml.Mark{}}},
+		&ssml.SayAs{Text: "12"},
+		&ssml.Sub{Text: "W3C"},
+		&ssml.Phoneme{Alphabet: "arpabet", PH: "T AH", Text: "the"},
+		&ssml.Mark{Name: "two words"},
+		nil,
+	}
+	for _, n := range invalid {
+		_, err := ssml.New("").Text("hello").Add(n).Build()
+		require.NotNil(t, err, "%#v", n)
+		require.Equal(t, errors.SSMLInvalid, err.(*errors.Error).Code)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	require.Nil(t, ssml.Check(`<speak>Hello <break time="1s"/> world</speak>`))
+
+	for _, markup := range []string{
+		"",
+		"hello",
+		"<speak>hello",
+		"<p>hello</p>",
+		"<speak>Tom & Jerry</speak>",
+	} {
+		err := ssml.Check(markup)
+		require.NotNil(t, err, markup)
+		require.Equal(t, errors.SSMLInvalid, err.(*errors.Error).Code)
+	}
+}
@@ -0,0 +1,50 @@
+package testutils
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/auroraapi/aurora-go/audio"
+)
+
+// Sine generates `n` samples of a sine wave with the given frequency (Hz) and
+// amplitude at the given sample rate.
+func Sine(freq float64, amp float64, sampleRate uint32, n int) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = amp * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate))
+	}
+	return s
+}
+
+// Noise generates `n` samples of uniform white noise with the given peak
+// amplitude. The seed makes the output reproducible.
+func Noise(amp float64, n int, seed int64) []float64 {
+	r := rand.New(rand.NewSource(seed))
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = amp * (2*r.Float64() - 1)
+	}
+	return s
+}
+
+// NewFile creates a 16-bit audio.File with the given sample rate from
+// floating point samples (one slice per channel).
+func NewFile(sampleRate uint32, channels ...[]float64) *audio.File {
+	wav := audio.NewWAVFromParams(&audio.WAVParams{
+		NumChannels:   uint16(len(channels)),
+		SampleRate:    sampleRate,
+		BitsPerSample: 16,
+	})
+	wav.SetSamples(channels)
+	return &audio.File{AudioData: wav}
+}
+
+// Energy returns the sum of the squares of the given samples.
+func Energy(s []float64) float64 {
+	e := 0.0
+	for _, v := range s {
+		e += v * v
+	}
+	return e
+}
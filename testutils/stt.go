@@ -0,0 +1,65 @@
+package testutils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+)
+
+// NewSTTStreamServer starts a stand-in for the streaming speech to text API,
+// which can be used as the backend's base URL and client in tests (see
+// `backend.NewAuroraBackendWithClient`). It uses HTTP/2, so that hypotheses
+// can be sent while audio is still being received.
+//
+// Each time a chunk of audio arrives, it sends an interim hypothesis made by
+// calling `transcribe` with all of the audio received so far. Once the audio
+// ends, it sends the final hypothesis. Requests without an application ID are
+// rejected with a `MissingApplicationID` error, like the real API.
+func NewSTTStreamServer(transcribe func(audio []byte) string) *httptest.Server {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/v1/stt/stream/" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"code": "NotFound"})
+			return
+		}
+		if r.Header.Get("X-Application-ID") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{
+				"code":    "MissingApplicationID",
+				"message": "An application ID is required to access this endpoint.",
+			})
+			return
+		}
+
+		encoder := json.NewEncoder(w)
+		send := func(audio []byte, final bool) {
+			encoder.Encode(map[string]interface{}{
+				"transcript": transcribe(audio),
+				"final":      final,
+			})
+			w.(http.Flusher).Flush()
+		}
+
+		audio := make([]byte, 0)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Body.Read(buf)
+			if n > 0 {
+				audio = append(audio, buf[:n]...)
+				send(audio, false)
+			}
+			if err == io.EOF {
+				send(audio, true)
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	return s
+}
@@ -0,0 +1,45 @@
+package testutils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// NewTTSStreamServer starts a stand-in for the text to speech API, which can
+// be used as the backend's base URL and client in tests. Like the real API,
+// it streams the audio in chunks as it is "synthesized": it sends `audio` in
+// chunks of `chunkSize` bytes, waiting `delay` before each chunk after the
+// first. Requests without an application ID are rejected with a
+// `MissingApplicationID` error.
+func NewTTSStreamServer(audio []byte, chunkSize int, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Application-ID") == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{
+				"code":    "MissingApplicationID",
+				"message": "An application ID is required to access this endpoint.",
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "audio/wav")
+		for i := 0; i < len(audio); i += chunkSize {
+			if i > 0 {
+				select {
+				case <-time.After(delay):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			end := i + chunkSize
+			if end > len(audio) {
+				end = len(audio)
+			}
+			w.Write(audio[i:end])
+			w.(http.Flusher).Flush()
+		}
+	}))
+}
@@ -1,7 +1,12 @@
 package aurora
 
 import (
+	"fmt"
+	"io"
+
 	"github.com/auroraapi/aurora-go/api"
+	"github.com/auroraapi/aurora-go/audio"
+	"github.com/auroraapi/aurora-go/errors"
 )
 
 // Text encapsulates some text, whether it is obtained from STT, a user input,
@@ -10,6 +15,30 @@ import (
 type Text struct {
 	// Text is the actual text that this object encapsulates
 	Text string
+
+	// Confidence, Words, Alternatives and Language are only set for text
+	// obtained from STT.
+
+	// Confidence is how confident the API is in the transcript, from 0 to
+	// 1 (0 if it wasn't reported).
+	Confidence float64
+	// Words are the words of the transcript, with their timings and
+	// confidences.
+	Words []*api.STTWord
+	// Alternatives are the next most likely transcripts, in order. Each can
+	// be used like any other Text, e.g. to try interpreting the next one if
+	// this one isn't understood.
+	Alternatives []*Text
+	// Language is the language detected in the speech, as a BCP-47 tag such
+	// as "en-US" (empty if it wasn't detected).
+	Language string
+
+	// TTSOptions configure how the text is spoken by `Speech`, e.g. to give
+	// each persona of an app its own voice (`nil` means the defaults).
+	TTSOptions *api.TTSOptions
+	// SSML is true if the text is an SSML document, which `Speech` speaks
+	// according to its markup.
+	SSML bool
 }
 
 // NewText creates a Text object from the given text.
@@ -17,17 +46,123 @@ func NewText(text string) *Text {
 	return &Text{Text: text}
 }
 
+// NewSSMLText creates a Text object from an SSML document, such as one made
+// with the `ssml` package. Only `Speech` understands the markup.
+func NewSSMLText(document string) *Text {
+	return &Text{Text: document, SSML: true}
+}
+
+// newTextFromSTT creates a Text object from a response from the STT API.
+func newTextFromSTT(r *api.STTResponse) *Text {
+	t := &Text{
+		Text:       r.Transcript,
+		Confidence: r.Confidence,
+		Words:      r.Words,
+		Language:   r.Language,
+	}
+	for i, alt := range r.Alternatives {
+		if i == 0 && alt.Transcript == r.Transcript {
+			// the first alternative is this transcript
+			continue
+		}
+		t.Alternatives = append(t.Alternatives, &Text{
+			Text:       alt.Transcript,
+			Confidence: alt.Confidence,
+			Words:      alt.Words,
+			Language:   r.Language,
+		})
+	}
+	return t
+}
+
 // Speech calls the Aurora TTS service on the text encapsulated in this object
 // and converts it to a `Speech` object. Further operations can then be done
 // on it, such as saving to file or speaking the resulting audio.
 func (t *Text) Speech() (*Speech, error) {
-	response, err := api.GetTTS(Config, t.Text)
+	response, err := api.GetTTSWithOptions(Config, t.Text, t.ttsOptions())
 	if err != nil {
 		return nil, err
 	}
 	return NewSpeech(response), nil
 }
 
+// TimedSpeech is like `Speech`, but the speech also has the timepoints of
+// each word (and each mark, for SSML), which `Speech.PlayWithTimepoints`
+// reports as they are played.
+func (t *Text) TimedSpeech() (*Speech, error) {
+	response, err := api.GetTTSWithTimepoints(Config, t.Text, t.ttsOptions())
+	if err != nil {
+		return nil, err
+	}
+	speech := NewSpeech(response.Audio)
+	speech.Timepoints = response.Timepoints
+	return speech, nil
+}
+
+// LongSpeech is like `Speech`, but for text of any length, such as an
+// article: the text is split into sentences that are synthesized concurrently
+// and joined with pauses (see `api.GetLongTTS`). Pass `nil` to use the
+// default parameters.
+func (t *Text) LongSpeech(params *api.LongTTSParams) (*Speech, error) {
+	response, err := api.GetLongTTS(Config, t.Text, t.ttsOptions(), params)
+	if err != nil {
+		return nil, err
+	}
+	return NewSpeech(response), nil
+}
+
+// SpeechStream calls the Aurora TTS service on the text encapsulated in this
+// object and returns the audio as it is synthesized, so that it can be used
+// before the whole text has been spoken. The stream must be closed once it
+// has been read.
+func (t *Text) SpeechStream() (*api.TTSStream, error) {
+	return api.StreamTTS(Config, t.Text, t.ttsOptions())
+}
+
+// SpeakTo converts the text to speech and writes the audio to `w` as it is
+// synthesized, e.g. to forward it to a client. It returns once all of the
+// audio has been written.
+func (t *Text) SpeakTo(w io.Writer) error {
+	stream, err := t.SpeechStream()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// Speak converts the text to speech and plays it as it is synthesized, which
+// starts much sooner than playing the audio from `Speech` when the text is
+// long. It returns once all of the audio has been played. Only WAV audio can
+// be played as it streams, so other encodings return an error without calling
+// the API (use `SpeakTo` for those).
+func (t *Text) Speak() error {
+	if o := t.TTSOptions; o != nil && o.Encoding != "" && o.Encoding != api.TTSEncodingWAV {
+		return errors.NewFromErrorCodeInfo(errors.TTSInvalidOptions, fmt.Sprintf("%q audio can't be played as it streams. Use SpeakTo instead.", o.Encoding))
+	}
+
+	stream, err := t.SpeechStream()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	return audio.PlayStream(stream)
+}
+
+// ttsOptions returns the options to speak the text with.
+func (t *Text) ttsOptions() *api.TTSOptions {
+	if !t.SSML {
+		return t.TTSOptions
+	}
+	o := api.TTSOptions{}
+	if t.TTSOptions != nil {
+		o = *t.TTSOptions
+	}
+	o.SSML = true
+	return &o
+}
+
 // Interpret calls the Aurora Interpret service on the text encapsulated in this
 // object and converts it to an `Interpret` object, which contains the results
 // from the API call.
@@ -1,11 +1,18 @@
 package aurora_test
 
 import (
+	"encoding/base64"
+	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	aurora "github.com/auroraapi/aurora-go"
+	"github.com/auroraapi/aurora-go/api"
+	"github.com/auroraapi/aurora-go/audio"
 	"github.com/auroraapi/aurora-go/errors"
+	"github.com/auroraapi/aurora-go/ssml"
+	"github.com/auroraapi/aurora-go/testutils"
 	"github.com/stretchr/testify/require"
 )
 
@@ -46,6 +53,146 @@ func TestTextInterpretMultipleEntities(t *testing.T) {
 	require.Equal(t, "tomorrow", i.Entities["time"])
 }
 
+// The entities' details can be looked up by name.
+func TestTextInterpretDetails(t *testing.T) {
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"intent": "weather",
+			"confidence": 0.6,
+			"entities": {"location": "los angeles"},
+			"intents": [{"intent": "weather", "score": 0.6}, {"intent": "time", "score": 0.3}],
+			"entity_details": [{"name": "location", "type": "location", "text": "LA", "start": 23, "end": 25,
+				"value": {"text": "los angeles"}}]
+		}`))
+	})
+
+	i, err := aurora.NewText("what is the weather in LA").Interpret()
+	require.Nil(t, err)
+	require.Equal(t, "weather", i.Intent)
+	require.Equal(t, "los angeles", i.Entities["location"])
+	require.Equal(t, 0.6, i.Confidence)
+	require.Len(t, i.Intents, 2)
+	require.Equal(t, "time", i.Intents[1].Intent)
+
+	location := i.Entity("location")
+	require.NotNil(t, location)
+	require.Equal(t, "LA", location.Text)
+	require.Equal(t, "los angeles", location.Value.Text)
+	require.Nil(t, i.Entity("time"))
+}
+
+// Each text can be spoken with its own voice.
+func TestTextSpeechOptions(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write(testutils.CreateEmptyWAVFile())
+	})
+
+	text := aurora.NewText("welcome back")
+	text.TTSOptions = &api.TTSOptions{Voice: "amy"}
+	speech, err := text.Speech()
+	require.Nil(t, err)
+	require.NotNil(t, speech.Audio)
+	q := received(t, requests).URL.Query()
+	require.Equal(t, "welcome back", q.Get("text"))
+	require.Equal(t, "amy", q.Get("voice"))
+}
+
+// SSML text is spoken according to its markup, with the text's options.
+func TestTextSpeechSSML(t *testing.T) {
+	doc, err := ssml.New("en-US").Text("Your code is").SayAs(ssml.InterpretCharacters, "", "A7X").Build()
+	require.Nil(t, err)
+	requests := make(chan *http.Request, 1)
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write(testutils.CreateEmptyWAVFile())
+	})
+
+	text := aurora.NewSSMLText(doc.String())
+	text.TTSOptions = &api.TTSOptions{Voice: "amy"}
+	speech, err := text.Speech()
+	require.Nil(t, err)
+	require.NotNil(t, speech.Audio)
+	require.False(t, text.TTSOptions.SSML)
+	q := received(t, requests).URL.Query()
+	require.Equal(t, doc.String(), q.Get("text"))
+	require.Equal(t, "ssml", q.Get("text_type"))
+	require.Equal(t, "amy", q.Get("voice"))
+}
+
+// Timed speech reports when each word is spoken.
+func TestTextTimedSpeech(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"audio": "` + base64.StdEncoding.EncodeToString(testutils.CreateEmptyWAVFile()) + `",
+			"timepoints": [
+				{"type": "word", "text": "good", "offset": 0, "start": 0.0, "end": 0.3},
+				{"type": "word", "text": "morning", "offset": 5, "start": 0.3, "end": 0.8}
+			]}`))
+	})
+
+	speech, err := aurora.NewText("good morning").TimedSpeech()
+	require.Nil(t, err)
+	require.Equal(t, "true", received(t, requests).URL.Query().Get("timepoints"))
+	require.NotNil(t, speech.Audio)
+	require.Len(t, speech.Timepoints, 2)
+
+	require.Equal(t, []*api.TTSTimepoint{speech.Timepoints[0]}, speech.TimepointsBetween(0, 0.3))
+	require.Equal(t, speech.Timepoints, speech.TimepointsBetween(0, 1))
+	require.Empty(t, speech.TimepointsBetween(0.8, 1))
+}
+
+// timedWriter records when each write happens.
+type timedWriter struct {
+	data  []byte
+	times []time.Time
+}
+
+func (w *timedWriter) Write(p []byte) (int, error) {
+	w.data = append(w.data, p...)
+	w.times = append(w.times, time.Now())
+	return len(p), nil
+}
+
+// Audio is written as it arrives, not once all of it has been synthesized.
+func TestTextSpeakTo(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	data := audio.NewWAVFromParams(&audio.WAVParams{AudioData: make([]byte, 32000)}).Data()
+	s := testutils.NewTTSStreamServer(data, 8000, delay)
+	defer s.Close()
+	withBackend(t, s.Config.Handler.ServeHTTP)
+	appID := aurora.Config.AppID
+	aurora.Config.AppID = "AppID"
+	defer func() { aurora.Config.AppID = appID }()
+
+	w := &timedWriter{}
+	require.Nil(t, aurora.NewText("hello").SpeakTo(w))
+	require.Equal(t, data, w.data)
+	require.True(t, len(w.times) > 1)
+	require.True(t, w.times[len(w.times)-1].Sub(w.times[0]) >= 3*delay)
+}
+
+// Audio that can't be played as it streams is rejected before calling the API.
+func TestTextSpeakEncoding(t *testing.T) {
+	requests := make(chan *http.Request, 3)
+	withBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	})
+
+	for _, encoding := range []api.TTSEncoding{api.TTSEncodingPCM, api.TTSEncodingMP3, api.TTSEncodingOpus} {
+		text := aurora.NewText("hello")
+		text.TTSOptions = &api.TTSOptions{Encoding: encoding}
+		err := text.Speak()
+		require.NotNil(t, err, string(encoding))
+		require.Equal(t, errors.TTSInvalidOptions, err.(*errors.Error).Code, string(encoding))
+	}
+	require.Len(t, requests, 0, "audio that can't be played shouldn't be requested")
+}
+
 // TestMain sets up testing parameters and runs all tests
 func TestMain(m *testing.M) {
 	// set configuration from environment